@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/aws/config"
+	"eclogin/pkg/aws/ec2"
+	"eclogin/pkg/aws/ecs"
+	"eclogin/pkg/aws/organizations"
+	"eclogin/pkg/prompt"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	aws_ecs "github.com/aws/aws-sdk-go-v2/service/ecs"
+	aws_organizations "github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultRoleName     = "OrganizationAccountAccessRole"
+	accountColumnFormat = "%-12s %-24s %s"
+)
+
+// accountConfig is the AWS config of a member account, with credentials from the assumed role.
+type accountConfig struct {
+	Account organizations.Account
+	Config  aws.Config
+}
+
+// accountItem is a resource found by searchAllAccounts, tagged with the account it lives in.
+type accountItem[T any] struct {
+	Account accountConfig
+	Item    T
+}
+
+type ecsServiceRef struct {
+	Cluster string
+	Service string
+}
+
+// listAccountConfigs returns a config for every account in --accounts, or for every
+// account of the organization when --accounts is not set.
+func listAccountConfigs(cmd *cobra.Command, cfg aws.Config) ([]accountConfig, error) {
+	roleName, _ := cmd.Flags().GetString("role-name")
+	accountIDs, _ := cmd.Flags().GetStringSlice("accounts")
+
+	var accounts []organizations.Account
+	if len(accountIDs) > 0 {
+		for _, id := range accountIDs {
+			accounts = append(accounts, organizations.Account{ID: id})
+		}
+	} else {
+		var err error
+		accounts, err = organizations.ListAccounts(cmd.Context(), aws_organizations.NewFromConfig(cfg))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	configs := make([]accountConfig, len(accounts))
+	for i, account := range accounts {
+		configs[i] = accountConfig{Account: account, Config: config.AssumeRoleConfig(cfg, account.ID, roleName)}
+	}
+	return configs, nil
+}
+
+// searchAllAccounts assumes the role in every account and calls search concurrently for each of them.
+// Accounts that fail are skipped; their errors are only returned when nothing was found.
+func searchAllAccounts[T any](cmd *cobra.Command, region, profile string, search func(cfg aws.Config) ([]T, error)) ([]accountItem[T], error) {
+	cfg, err := config.LoadConfig(cmd.Context(), region, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := listAccountConfigs(cmd, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	accountIDs := make([]string, len(accounts))
+	configs := make(map[string]aws.Config, len(accounts))
+	for i, account := range accounts {
+		accountIDs[i] = account.Account.ID
+		configs[account.Account.ID] = account.Config
+	}
+
+	results, err := searchConcurrently(accountIDs, func(accountID string) ([]T, error) {
+		return search(configs[accountID])
+	})
+
+	var items []accountItem[T]
+	for i, account := range accounts {
+		for _, item := range results[i] {
+			items = append(items, accountItem[T]{Account: account, Item: item})
+		}
+	}
+
+	if len(items) == 0 && err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// selectInstanceInAllAccounts lists instances in every account and returns the account config and ID of the selected one.
+func selectInstanceInAllAccounts(cmd *cobra.Command, region, profile string, prompter prompt.Prompter) (aws.Config, string, error) {
+	ctx := cmd.Context()
+	instances, err := searchAllAccounts(cmd, region, profile, func(cfg aws.Config) ([]ec2.Instance, error) {
+		return ec2.ListInstances(ctx, aws_ec2.NewFromConfig(cfg))
+	})
+	if err != nil {
+		return aws.Config{}, "", apperr.Wrap(err, "failed to list EC2 instances")
+	}
+	if len(instances) == 0 {
+		return aws.Config{}, "", apperr.NotFound("no EC2 instances found")
+	}
+
+	displayNames := make([]string, len(instances))
+	for i, instance := range instances {
+		displayNames[i] = fmt.Sprintf(accountColumnFormat, instance.Account.Account.ID, instance.Account.Account.Name, instance.Item.DisplayName())
+	}
+
+	selected, err := prompter.Select("Select EC2 Instance", displayNames)
+	if err != nil {
+		return aws.Config{}, "", err
+	}
+	for i, displayName := range displayNames {
+		if displayName == selected {
+			return instances[i].Account.Config, instances[i].Item.ID, nil
+		}
+	}
+	return aws.Config{}, "", apperr.NotFound("selected instance not found: %s", selected)
+}
+
+// selectServiceInAllAccounts lists the services of every cluster in every account and returns
+// the account config, cluster and service of the selected one.
+func selectServiceInAllAccounts(cmd *cobra.Command, region, profile string, prompter prompt.Prompter) (aws.Config, string, string, error) {
+	ctx := cmd.Context()
+	services, err := searchAllAccounts(cmd, region, profile, func(cfg aws.Config) ([]ecsServiceRef, error) {
+		client := aws_ecs.NewFromConfig(cfg)
+		clusters, err := ecs.ListClusters(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+
+		var refs []ecsServiceRef
+		for _, cluster := range clusters {
+			services, err := ecs.ListServices(ctx, client, cluster)
+			if err != nil {
+				continue
+			}
+			for _, service := range services {
+				refs = append(refs, ecsServiceRef{Cluster: cluster, Service: service})
+			}
+		}
+		return refs, nil
+	})
+	if err != nil {
+		return aws.Config{}, "", "", apperr.Wrap(err, "failed to list ECS services")
+	}
+	if len(services) == 0 {
+		return aws.Config{}, "", "", apperr.NotFound("no ECS services found")
+	}
+
+	displayNames := make([]string, len(services))
+	for i, service := range services {
+		displayNames[i] = fmt.Sprintf(accountColumnFormat, service.Account.Account.ID, service.Account.Account.Name, service.Item.Cluster+"/"+service.Item.Service)
+	}
+
+	selected, err := prompter.Select("Select ECS Service", displayNames)
+	if err != nil {
+		return aws.Config{}, "", "", err
+	}
+	for i, displayName := range displayNames {
+		if displayName == selected {
+			return services[i].Account.Config, services[i].Item.Cluster, services[i].Item.Service, nil
+		}
+	}
+	return aws.Config{}, "", "", apperr.NotFound("selected service not found: %s", selected)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/audit"
+	"eclogin/pkg/aws/sts"
+	"eclogin/pkg/settings"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_sts "github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// auditLogger returns the audit logger configured in the settings file, or nil when auditing is disabled.
+func auditLogger() (*audit.Logger, error) {
+	config := userSettings.Audit
+	if config.Disabled {
+		return nil, nil
+	}
+
+	path := config.Path
+	if path == "" {
+		dir, err := settings.Dir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "audit.jsonl")
+	} else {
+		var err error
+		if path, err = expandHome(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var forwarders []audit.Forwarder
+	if config.Syslog != nil {
+		forwarders = append(forwarders, audit.NewSyslogForwarder(config.Syslog.Network, config.Syslog.Address))
+	}
+	if config.HTTP != nil {
+		forwarders = append(forwarders, audit.NewHTTPForwarder(config.HTTP.URL, config.HTTP.Headers))
+	}
+	return audit.NewLogger(path, forwarders...), nil
+}
+
+// auditSession logs the start of a session, with the caller identity of cfg when it is not nil,
+// and returns a function that logs its end. Failures are only reported, since they should not
+// prevent the session.
+func auditSession(ctx context.Context, cfg *aws.Config, event audit.Event) func(err error) {
+	logger, err := auditLogger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open audit log: %v\n", err)
+		return func(error) {}
+	}
+	if logger == nil {
+		return func(error) {}
+	}
+
+	if cfg != nil {
+		event.Region = cfg.Region
+		if identity, err := sts.GetCallerIdentity(ctx, aws_sts.NewFromConfig(*cfg)); err == nil {
+			event.CallerARN = identity.ARN
+			event.UserID = identity.UserID
+			event.Account = identity.Account
+		}
+	}
+
+	start := time.Now()
+	event.Event = audit.EventSessionStart
+	event.Time = start
+	logAuditEvent(logger, event)
+
+	return func(err error) {
+		exitCode := audit.ExitCode(err)
+		event.Event = audit.EventSessionEnd
+		event.Time = time.Now()
+		event.DurationSeconds = event.Time.Sub(start).Seconds()
+		event.ExitCode = &exitCode
+		if err != nil {
+			event.Error = err.Error()
+		}
+		logAuditEvent(logger, event)
+	}
+}
+
+func logAuditEvent(logger *audit.Logger, event audit.Event) {
+	if err := logger.Log(event); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write audit log: %v\n", err)
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/aws/sts"
+	"eclogin/pkg/cache"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_sts "github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+// accountCacheTTL is long because the account behind a profile rarely changes.
+const accountCacheTTL = 24 * time.Hour
+
+// defaultCacheTTLs are the TTLs of the resource listings, overridable with cache_ttl in the settings file.
+var defaultCacheTTLs = map[string]time.Duration{
+	"clusters":  time.Hour,
+	"services":  time.Hour,
+	"instances": 10 * time.Minute,
+	"tasks":     time.Minute,
+}
+
+// resourceScope identifies the account, region and profile that cached listings belong to.
+// It is empty until setScope is called, which disables the cache.
+var resourceScope string
+
+// setScope makes the following pickers use the favorites and cached listings of the account and region of cfg.
+func setScope(ctx context.Context, cfg aws.Config, profile string) {
+	applyScope(callerAccount(ctx, cfg, profile), cfg.Region, profile)
+}
+
+// setAssumedScope is setScope for the credentials of roleName assumed in a member account. The account is
+// asked from STS every time, since the per-profile cache holds the account of the source profile.
+// When it cannot be determined, the cache stays disabled.
+func setAssumedScope(ctx context.Context, cfg aws.Config, roleName string) {
+	identity, err := sts.GetCallerIdentity(ctx, aws_sts.NewFromConfig(cfg))
+	if err != nil {
+		resourceScope = ""
+		return
+	}
+	applyScope(identity.Account, cfg.Region, "role/"+roleName)
+}
+
+func applyScope(account, region, principal string) {
+	resourceScope = strings.Join([]string{account, region, principal}, "|")
+	if favoritesStore != nil {
+		favoritesStore.SetScope(account, region)
+	}
+}
+
+// callerAccount returns the account ID of cfg. It is cached per profile and region to avoid an STS call on every run.
+func callerAccount(ctx context.Context, cfg aws.Config, profile string) string {
+	resourceCache := diskCache()
+	key := strings.Join([]string{"account", profile, cfg.Region}, "|")
+
+	var account string
+	if resourceCache != nil && resourceCache.Get(key, accountCacheTTL, &account) {
+		return account
+	}
+
+	identity, err := sts.GetCallerIdentity(ctx, aws_sts.NewFromConfig(cfg))
+	if err != nil {
+		return ""
+	}
+	if resourceCache != nil {
+		_ = resourceCache.Set(key, identity.Account)
+	}
+	return identity.Account
+}
+
+// cachedList returns the listing of kind from the on-disk cache and refreshes it in the background.
+// The listing is fetched synchronously on a cache miss, with --refresh, or when no scope is set.
+func cachedList[T any](cmd *cobra.Command, kind string, keyParts []string, fetch func() ([]T, error)) ([]T, error) {
+	resourceCache := diskCache()
+	ttl := cacheTTL(kind)
+	if resourceCache == nil || resourceScope == "" || ttl <= 0 {
+		return fetch()
+	}
+
+	key := strings.Join(append([]string{"resources", kind, resourceScope}, keyParts...), "|")
+	if refresh, _ := cmd.Flags().GetBool("refresh"); !refresh {
+		var items []T
+		if resourceCache.Get(key, ttl, &items) {
+			go refreshCache(resourceCache, key, fetch)
+			return items, nil
+		}
+	}
+
+	items, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if err := resourceCache.Set(key, items); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to cache %s: %v\n", kind, err)
+	}
+	return items, nil
+}
+
+// refreshCache updates a cached listing. Errors are ignored since the cached listing is already in use.
+func refreshCache[T any](resourceCache *cache.Cache, key string, fetch func() ([]T, error)) {
+	items, err := fetch()
+	if err != nil {
+		return
+	}
+	_ = resourceCache.Set(key, items)
+}
+
+func diskCache() *cache.Cache {
+	dir, err := cache.Dir()
+	if err != nil {
+		return nil
+	}
+	return cache.New(dir)
+}
+
+func cacheTTL(kind string) time.Duration {
+	if ttl, ok := userSettings.CacheTTL[kind]; ok {
+		return ttl
+	}
+	return defaultCacheTTLs[kind]
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/aws/config"
+	"eclogin/pkg/aws/ec2"
+	"eclogin/pkg/aws/ecs"
+	"eclogin/pkg/aws/session"
+	"eclogin/pkg/output"
+	"eclogin/pkg/prompt"
+	"eclogin/pkg/settings"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	aws_ecs "github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/cobra"
+)
+
+// completionCacheTTL keeps results between TAB presses without serving stale resources for long.
+const completionCacheTTL = 30 * time.Second
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// completeAWS returns a completion function that lists candidates with the AWS config from
+// --region and --profile on the command line. Results are cached for completionCacheTTL.
+func completeAWS(kind string, requiredFlags []string, list func(cmd *cobra.Command, cfg aws.Config) ([]string, error)) completionFunc {
+	return func(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		// Completion must never block on a prompt, an MFA code or an SSO login.
+		prompt.SetInteractive(false)
+
+		region, profile := completionRegionProfile(cmd)
+		keyParts := []string{kind, profile, region}
+		for _, flag := range requiredFlags {
+			value, _ := cmd.Flags().GetString(flag)
+			if value == "" {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			keyParts = append(keyParts, value)
+		}
+
+		var candidates []string
+		completionCache := diskCache()
+		key := strings.Join(keyParts, "|")
+		if completionCache != nil && completionCache.Get(key, completionCacheTTL, &candidates) {
+			return candidates, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		// PersistentPreRunE does not run for completion requests either, and TAB must not hang
+		// on an unreachable endpoint, so the whole lookup is limited to defaultTimeout too.
+		applyAWSFlags(cmd)
+		ctx, cancel := context.WithTimeout(cmd.Context(), defaultTimeout)
+		defer cancel()
+		cmd.SetContext(ctx)
+
+		cfg, err := config.LoadConfig(ctx, region, profile)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		candidates, err = list(cmd, cfg)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		if completionCache != nil {
+			_ = completionCache.Set(key, candidates)
+		}
+		return candidates, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completionRegionProfile returns the region and profile of the command line, falling back to the settings file.
+// Settings are loaded here because PersistentPreRun does not run for completion requests.
+func completionRegionProfile(cmd *cobra.Command) (string, string) {
+	if loaded, err := settings.Load(); err == nil {
+		userSettings = loaded
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	if region == "" {
+		region = preferredRegion()
+	}
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile == "" {
+		profile = userSettings.DefaultProfile
+	}
+	return region, profile
+}
+
+var completeClusters = completeAWS("clusters", nil, func(cmd *cobra.Command, cfg aws.Config) ([]string, error) {
+	return ecs.ListClusters(cmd.Context(), aws_ecs.NewFromConfig(cfg))
+})
+
+var completeServices = completeAWS("services", []string{"cluster"}, func(cmd *cobra.Command, cfg aws.Config) ([]string, error) {
+	cluster, _ := cmd.Flags().GetString("cluster")
+	return ecs.ListServices(cmd.Context(), aws_ecs.NewFromConfig(cfg), cluster)
+})
+
+var completeTaskIDs = completeAWS("tasks", []string{"cluster", "service"}, func(cmd *cobra.Command, cfg aws.Config) ([]string, error) {
+	cluster, _ := cmd.Flags().GetString("cluster")
+	service, _ := cmd.Flags().GetString("service")
+	return ecs.ListTaskIDs(cmd.Context(), aws_ecs.NewFromConfig(cfg), cluster, service)
+})
+
+var completeContainers = completeAWS("containers", []string{"cluster", "task-id"}, func(cmd *cobra.Command, cfg aws.Config) ([]string, error) {
+	cluster, _ := cmd.Flags().GetString("cluster")
+	taskID, _ := cmd.Flags().GetString("task-id")
+	containerInfo, err := ecs.GetContainerInfo(cmd.Context(), aws_ecs.NewFromConfig(cfg), cluster, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := ecs.ListContainerNames(containerInfo)
+	sort.Strings(containers)
+	return containers, nil
+})
+
+// completeInstanceIDs offers instance IDs with their Name tag as the description.
+var completeInstanceIDs = completeAWS("instances", nil, func(cmd *cobra.Command, cfg aws.Config) ([]string, error) {
+	instances, err := ec2.ListInstances(cmd.Context(), aws_ec2.NewFromConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]string, len(instances))
+	for i, instance := range instances {
+		candidates[i] = instance.ID + "\t" + instance.Name
+	}
+	return candidates, nil
+})
+
+var completeDocuments = completeAWS("documents", nil, func(cmd *cobra.Command, cfg aws.Config) ([]string, error) {
+	return session.ListSessionDocuments(cmd.Context(), ssm.NewFromConfig(cfg))
+})
+
+var completeRegions = completeAWS("regions", nil, func(cmd *cobra.Command, cfg aws.Config) ([]string, error) {
+	return ec2.ListRegions(cmd.Context(), aws_ec2.NewFromConfig(cfg))
+})
+
+func completeAliases(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	loaded, err := settings.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	aliases := make([]string, 0, len(loaded.Aliases))
+	for name, alias := range loaded.Aliases {
+		aliases = append(aliases, name+"\t"+alias.Type)
+	}
+	sort.Strings(aliases)
+	return aliases, cobra.ShellCompDirectiveNoFileComp
+}
+
+// registerFlagCompletions registers the completion functions for the flags the command has.
+func registerFlagCompletions(cmd *cobra.Command) {
+	completions := map[string]completionFunc{
+		"region":        completeRegions,
+		"cluster":       completeClusters,
+		"service":       completeServices,
+		"task-id":       completeTaskIDs,
+		"container":     completeContainers,
+		"instance-id":   completeInstanceIDs,
+		"document-name": completeDocuments,
+		"shell":         cobra.FixedCompletions(availableShells, cobra.ShellCompDirectiveNoFileComp),
+		"output":        cobra.FixedCompletions(output.Formats, cobra.ShellCompDirectiveNoFileComp),
+		"strategy":      cobra.FixedCompletions(poolStrategies, cobra.ShellCompDirectiveNoFileComp),
+	}
+
+	for flag, complete := range completions {
+		if cmd.Flags().Lookup(flag) != nil {
+			_ = cmd.RegisterFlagCompletionFunc(flag, complete)
+		}
+	}
+}
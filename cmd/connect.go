@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/aws/config"
+	"eclogin/pkg/aws/ec2"
+	"eclogin/pkg/aws/ecs"
+	"eclogin/pkg/history"
+	"eclogin/pkg/prompt"
+	"eclogin/pkg/settings"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	aws_ecs "github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/spf13/cobra"
+)
+
+const instanceStateRunning = "running"
+
+var connectCmd = &cobra.Command{
+	Use:   "connect <alias>",
+	Short: "Start a session with a target defined as an alias in the settings file",
+	Long: `The connect command starts a session with a target defined in the aliases section
+of ~/.config/eclogin/config.yaml. For ECS aliases a running task of the service is picked
+automatically, and for EC2 aliases a running instance with the given Name tag.
+An EC2 alias with a command runs it instead of a shell.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeAliases,
+	RunE:              runConnectCommand,
+}
+
+func runConnectCommand(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	alias, err := userSettings.Alias(args[0])
+	if err != nil {
+		return apperr.Wrap(err, "failed to resolve alias")
+	}
+
+	profile := alias.Profile
+	if profile == "" {
+		profile = userSettings.DefaultProfile
+	}
+	region := alias.Region
+	if region == "" {
+		region = preferredRegion()
+	}
+
+	cfg, err := config.LoadConfig(ctx, region, profile)
+	if err != nil {
+		return apperr.Wrap(err, "failed to load AWS config")
+	}
+
+	switch alias.Type {
+	case settings.TargetTypeEC2:
+		instanceID, err := resolveAliasInstance(ctx, cfg, alias)
+		if err != nil {
+			return apperr.Wrap(err, "failed to resolve EC2 instance")
+		}
+
+		fmt.Printf("Connecting to %s\n\n", instanceID)
+		entry := history.Entry{Type: settings.TargetTypeEC2, Profile: profile, InstanceID: instanceID}
+		if alias.Command != "" {
+			entry.Document, entry.Parameters = interactiveCommand(alias.Command)
+		}
+		started := time.Now()
+		if err := executeInstanceSession(ctx, cfg, entry); err != nil {
+			return apperr.Wrap(reconnectDropped(ctx, cfg, entry, started, err), "failed to execute instance session")
+		}
+		recordHistory(ctx, cfg, profile, entry)
+	case settings.TargetTypeECS:
+		started := time.Now()
+		entry, err := connectECSAlias(ctx, cfg, alias, newPrompter())
+		entry.Profile = profile
+		if err != nil {
+			return apperr.Wrap(reconnectDropped(ctx, cfg, entry, started, err), "failed to execute container session")
+		}
+		recordHistory(ctx, cfg, profile, entry)
+	}
+	return nil
+}
+
+// resolveAliasInstance returns the instance ID of the alias, or the first running instance with its Name tag.
+func resolveAliasInstance(ctx context.Context, cfg aws.Config, alias settings.Alias) (string, error) {
+	if alias.InstanceID != "" {
+		return alias.InstanceID, nil
+	}
+	if alias.Name == "" {
+		return "", apperr.InvalidInput("either instance_id or name is required for ec2 aliases")
+	}
+
+	instances, err := ec2.ListInstances(ctx, aws_ec2.NewFromConfig(cfg))
+	if err != nil {
+		return "", err
+	}
+
+	for _, instance := range instances {
+		if instance.Name == alias.Name && instance.State == instanceStateRunning {
+			return instance.ID, nil
+		}
+	}
+	return "", apperr.NotFound("no running instance named %s", alias.Name)
+}
+
+// connectECSAlias picks a running task of the alias service and starts a session with its container.
+// It returns the history entry of the connection.
+func connectECSAlias(ctx context.Context, cfg aws.Config, alias settings.Alias, prompter prompt.Prompter) (history.Entry, error) {
+	if alias.Cluster == "" || alias.Service == "" {
+		return history.Entry{}, apperr.InvalidInput("cluster and service are required for ecs aliases")
+	}
+
+	client := aws_ecs.NewFromConfig(cfg)
+	taskIDs, err := ecs.ListTaskIDs(ctx, client, alias.Cluster, alias.Service)
+	if err != nil {
+		return history.Entry{}, err
+	}
+	if len(taskIDs) == 0 {
+		return history.Entry{}, apperr.NotFound("no tasks found for service %s in cluster %s", alias.Service, alias.Cluster)
+	}
+	taskID := taskIDs[0]
+
+	containerInfo, err := ecs.GetContainerInfo(ctx, client, alias.Cluster, taskID)
+	if err != nil {
+		return history.Entry{}, err
+	}
+
+	container := alias.Container
+	if container == "" {
+		containers := ecs.ListContainerNames(containerInfo)
+		if len(containers) == 1 {
+			container = containers[0]
+		} else {
+			container, err = prompter.Select("Select ECS Container", containers)
+			if err != nil {
+				return history.Entry{}, err
+			}
+		}
+	}
+
+	runtimeID, ok := containerInfo[container]
+	if !ok {
+		return history.Entry{}, apperr.NotFound("container %s not found in task %s", container, taskID)
+	}
+
+	shell := alias.Shell
+	if shell == "" {
+		shell = userSettings.Shell
+	}
+	if shell == "" {
+		shell = defaultShellSh
+	}
+
+	fmt.Printf("Connecting to %s/%s task %s container %s\n\n", alias.Cluster, alias.Service, taskID, container)
+	entry := history.Entry{
+		Type:      settings.TargetTypeECS,
+		Cluster:   alias.Cluster,
+		Service:   alias.Service,
+		TaskID:    taskID,
+		Container: container,
+		Shell:     shell,
+	}
+	return entry, executeContainerSession(ctx, cfg, shell, taskID, alias.Cluster, container, runtimeID)
+}
+
+func init() {
+	rootCmd.AddCommand(connectCmd)
+}
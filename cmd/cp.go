@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/audit"
+	"eclogin/pkg/aws/ecs"
+	"eclogin/pkg/aws/session"
+	"eclogin/pkg/settings"
+	"eclogin/pkg/transfer"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_ecs "github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const interactiveCommandDocumentName = "AWS-StartInteractiveCommand"
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <source> <destination>",
+	Short: "Copy a file to or from an EC2 instance, ECS container or local Docker container",
+	Long: `The cp command copies a regular file between this machine and an instance or container.
+One of the source and the destination is a path on this machine, and the other one of:
+
+  ec2://<name-or-id>/path                     EC2 instance, over a Session Manager session
+  ecs://<cluster>/<service>/<container>/path  container of the first running task of the service, over ECS Exec
+  local://<container>/path                    local Docker container
+
+Over Session Manager and ECS Exec, the file is sent as base64, which requires sh and base64 on the target.
+A destination ending with / is a directory, and the file keeps its name.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCpCommand,
+}
+
+func runCpCommand(cmd *cobra.Command, args []string) error {
+	src, err := transfer.Parse(args[0])
+	if err != nil {
+		return apperr.InvalidInput("invalid source: %v", err)
+	}
+	dst, err := transfer.Parse(args[1])
+	if err != nil {
+		return apperr.InvalidInput("invalid destination: %v", err)
+	}
+
+	switch {
+	case src.Remote() && dst.Remote():
+		return apperr.InvalidInput("copying between two instances or containers is not supported")
+	case !src.Remote() && !dst.Remote():
+		return apperr.InvalidInput("either the source or the destination must be an ec2://, ecs:// or local:// location")
+	case dst.Remote():
+		return upload(cmd, src.Path, dst)
+	default:
+		return download(cmd, src, dst.Path)
+	}
+}
+
+func upload(cmd *cobra.Command, localPath string, dst transfer.Location) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return apperr.Wrap(err, "failed to open %s", localPath)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return apperr.Wrap(err, "failed to open %s", localPath)
+	}
+	if !info.Mode().IsRegular() {
+		return apperr.InvalidInput("%s is not a regular file", localPath)
+	}
+	if strings.HasSuffix(dst.Path, "/") {
+		dst.Path += filepath.Base(localPath)
+	}
+
+	progress := newProgress(dst.Base(), info.Size())
+	content := io.TeeReader(file, progress)
+
+	if dst.Scheme == transfer.SchemeLocal {
+		err = copyToDocker(cmd.Context(), dst, content, info)
+	} else {
+		err = uploadOverSession(cmd, dst, content)
+	}
+	if err != nil {
+		return apperr.Wrap(err, "failed to copy %s to %s", localPath, dst)
+	}
+	progress.Finish()
+	return nil
+}
+
+func download(cmd *cobra.Command, src transfer.Location, localPath string) error {
+	if info, err := os.Stat(localPath); (err == nil && info.IsDir()) || strings.HasSuffix(localPath, string(filepath.Separator)) {
+		localPath = filepath.Join(localPath, src.Base())
+	}
+
+	// The file is written next to its destination and renamed, so that a failed copy leaves no partial file.
+	file, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".*")
+	if err != nil {
+		return apperr.Wrap(err, "failed to create %s", localPath)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	progress := newProgress(src.Base(), 0)
+	content := io.MultiWriter(file, progress)
+
+	if src.Scheme == transfer.SchemeLocal {
+		err = copyFromDocker(cmd.Context(), src, content, progress)
+	} else {
+		err = downloadOverSession(cmd, src, content, progress)
+	}
+	if errors.Is(err, transfer.ErrNotReadable) {
+		return apperr.NotFound("%s is not a readable regular file", src)
+	}
+	if err != nil {
+		return apperr.Wrap(err, "failed to copy %s to %s", src, localPath)
+	}
+
+	if err := file.Close(); err != nil {
+		return apperr.Wrap(err, "failed to write %s", localPath)
+	}
+	if err := os.Rename(file.Name(), localPath); err != nil {
+		return apperr.Wrap(err, "failed to write %s", localPath)
+	}
+	progress.Finish()
+	return nil
+}
+
+// newProgress returns a progress line on stderr, or one that prints nothing when stderr is not a terminal.
+func newProgress(name string, total int64) *transfer.Progress {
+	var w io.Writer = io.Discard
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		w = os.Stderr
+	}
+	return transfer.NewProgress(w, name, total)
+}
+
+func uploadOverSession(cmd *cobra.Command, dst transfer.Location, content io.Reader) error {
+	token, err := transfer.NewToken()
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(transfer.Encode(pw, content))
+	}()
+
+	output := transfer.NewMarkerWriter(token)
+	if err := streamCommand(cmd, dst, transfer.UploadCommand(dst.Path, token), pr, output); err != nil {
+		return err
+	}
+	if !output.Found() {
+		return fmt.Errorf("failed to write %s, check that the directory exists and is writable", dst.Path)
+	}
+	return nil
+}
+
+func downloadOverSession(cmd *cobra.Command, src transfer.Location, content io.Writer, progress *transfer.Progress) error {
+	token, err := transfer.NewToken()
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	decoded := make(chan error, 1)
+	go func() {
+		err := transfer.Decode(pr, content, token, progress.SetTotal)
+		// Keep reading until the session ends, so that the plugin does not block on its output.
+		_, _ = io.Copy(io.Discard, pr)
+		decoded <- err
+	}()
+
+	err = streamCommand(cmd, src, transfer.DownloadCommand(src.Path, token), nil, pw)
+	pw.Close()
+	if decodeErr := <-decoded; decodeErr != nil && (err == nil || errors.Is(decodeErr, transfer.ErrNotReadable)) {
+		return decodeErr
+	}
+	return err
+}
+
+// streamCommand runs command in the instance or container of loc with its input and output
+// connected to stdin and stdout.
+func streamCommand(cmd *cobra.Command, loc transfer.Location, command string, stdin io.Reader, stdout io.Writer) error {
+	ctx := cmd.Context()
+	cfg, err := loadListConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	switch loc.Scheme {
+	case transfer.SchemeEC2:
+		instanceID := loc.Target[0]
+		if !isInstanceID(instanceID) {
+			if instanceID, err = resolveAliasInstance(ctx, cfg, settings.Alias{Name: instanceID}); err != nil {
+				return err
+			}
+		}
+		return streamInstanceCommand(ctx, cfg, instanceID, command, stdin, stdout)
+	case transfer.SchemeECS:
+		cluster, service, container := loc.Target[0], loc.Target[1], loc.Target[2]
+		client := aws_ecs.NewFromConfig(cfg)
+		taskIDs, err := ecs.ListTaskIDs(ctx, client, cluster, service)
+		if err != nil {
+			return err
+		}
+		if len(taskIDs) == 0 {
+			return apperr.NotFound("no tasks found for service %s in cluster %s", service, cluster)
+		}
+		containerInfo, err := ecs.GetContainerInfo(ctx, client, cluster, taskIDs[0])
+		if err != nil {
+			return err
+		}
+		runtimeID, ok := containerInfo[container]
+		if !ok {
+			return apperr.NotFound("container %s not found in task %s", container, taskIDs[0])
+		}
+		return streamContainerCommand(ctx, cfg, cluster, taskIDs[0], container, runtimeID, command, stdin, stdout)
+	default:
+		return apperr.InvalidInput("unsupported scheme: %s", loc.Scheme)
+	}
+}
+
+func streamInstanceCommand(ctx context.Context, cfg aws.Config, instanceID, command string, stdin io.Reader, stdout io.Writer) error {
+	document, parameters := interactiveCommand(command)
+	sessionInput := &ssm.StartSessionInput{
+		Target:       aws.String(instanceID),
+		DocumentName: aws.String(document),
+		Parameters:   parameters,
+	}
+	ssmClient := ssm.NewFromConfig(cfg)
+
+	sessionOutput, err := ssmClient.StartSession(ctx, sessionInput)
+	if err != nil {
+		return fmt.Errorf("start session failed: %w", err)
+	}
+	defer terminateSession(ctx, cfg, aws.ToString(sessionOutput.SessionId))
+
+	sessionData, err := json.Marshal(sessionOutput)
+	if err != nil {
+		return fmt.Errorf("marshal session failed: %w", err)
+	}
+	inputData, err := json.Marshal(sessionInput)
+	if err != nil {
+		return fmt.Errorf("marshal input failed: %w", err)
+	}
+	endpoint, err := session.ResolveEndpoint(ctx, ssmClient)
+	if err != nil {
+		return err
+	}
+
+	endAudit := auditSession(ctx, &cfg, audit.Event{
+		Type:      settings.TargetTypeEC2,
+		Target:    instanceID,
+		SessionID: aws.ToString(sessionOutput.SessionId),
+	})
+	err = session.RunSession(ctx, sessionData, inputData, cfg.Region, endpoint, stdin, stdout)
+	endAudit(err)
+	return err
+}
+
+func streamContainerCommand(ctx context.Context, cfg aws.Config, cluster, taskID, container, runtimeID, command string, stdin io.Reader, stdout io.Writer) error {
+	out, err := ecs.ExecuteContainerCommand(ctx, aws_ecs.NewFromConfig(cfg), command, taskID, cluster, container)
+	if err != nil {
+		return fmt.Errorf("execute command failed: %w", err)
+	}
+	defer terminateSession(ctx, cfg, aws.ToString(out.Session.SessionId))
+
+	sessionJSON, err := json.Marshal(out.Session)
+	if err != nil {
+		return fmt.Errorf("marshal session failed: %w", err)
+	}
+	inputJSON, err := json.Marshal(ssm.StartSessionInput{Target: aws.String(fmt.Sprintf(targetFormat, cluster, taskID, runtimeID))})
+	if err != nil {
+		return fmt.Errorf("marshal input failed: %w", err)
+	}
+	endpoint, err := session.ResolveEndpoint(ctx, ssm.NewFromConfig(cfg))
+	if err != nil {
+		return err
+	}
+
+	endAudit := auditSession(ctx, &cfg, audit.Event{
+		Type:      settings.TargetTypeECS,
+		Target:    fmt.Sprintf("%s/%s/%s", cluster, taskID, container),
+		SessionID: aws.ToString(out.Session.SessionId),
+	})
+	err = session.RunSession(ctx, sessionJSON, inputJSON, cfg.Region, endpoint, stdin, stdout)
+	endAudit(err)
+	return err
+}
+
+func copyToDocker(ctx context.Context, dst transfer.Location, content io.Reader, info os.FileInfo) error {
+	executor, err := newDockerExecutor(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Docker: %w", err)
+	}
+	return executor.copyToContainer(dst.Target[0], dst.Path, content, info.Size(), info.Mode())
+}
+
+func copyFromDocker(ctx context.Context, src transfer.Location, content io.Writer, progress *transfer.Progress) error {
+	executor, err := newDockerExecutor(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Docker: %w", err)
+	}
+	return executor.copyFromContainer(src.Target[0], src.Path, content, progress.SetTotal)
+}
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+}
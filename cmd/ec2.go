@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/audit"
 	"eclogin/pkg/aws/config"
 	"eclogin/pkg/aws/ec2"
 	"eclogin/pkg/aws/session"
+	"eclogin/pkg/history"
 	"eclogin/pkg/prompt"
+	"eclogin/pkg/recording"
+	"eclogin/pkg/settings"
 	"encoding/json"
 	"fmt"
-	"log"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	aws_ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -16,108 +23,400 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	instanceStatePending  = "pending"
+	instanceStateStopping = "stopping"
+	instanceStateStopped  = "stopped"
+
+	instanceStateMaxWait = 10 * time.Minute
+	agentMaxWait         = 5 * time.Minute
+	agentPollInterval    = 5 * time.Second
+)
+
 var ec2Cmd = &cobra.Command{
 	Use:   "ec2",
 	Short: "Start an interactive session with an EC2 instance using AWS Systems Manager",
 	Long: `The ec2 command allows you to start an interactive session with an EC2 instance
 using AWS Systems Manager. You can select an instance from the list of running instances
-and establish a session to manage it remotely.`,
-	Run: runEC2command,
+and establish a session to manage it remotely.
+
+With --asg or --target-group, only the healthy instances in service of the Auto Scaling group
+or target group are listed, and --strategy picks one of them without prompting:
+random, newest (latest launch time) or least-recent (least recently connected to).
+
+With --command, the command runs instead of a shell, e.g. --command "sudo su - app".`,
+	RunE: runEC2command,
 }
 
-func runEC2command(cmd *cobra.Command, _ []string) {
+func runEC2command(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
 	requiredFlags := []string{"instance-id", "region"}
-	prompter := prompt.NewUIPrompter()
-	region := prompt.GetFlagOrInput(cmd, "region", "Please enter AWS region (default: ap-northeast-1)", "ap-northeast-1", prompter)
-
-	var profile string
-	if prompt.HasRequiredFlags(cmd, requiredFlags) {
-		profile = cmd.Flag("profile").Value.String()
-	} else {
-		profile = prompt.GetFlagOrInput(cmd, "profile", "Please enter AWS profile (optional)", "", prompter)
-	}
+	prompter := newPrompter()
 
-	cfg, err := config.LoadConfig(region, profile)
+	profile, err := getProfile(cmd, requiredFlags, prompter)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return err
 	}
 
-	ec2Client := aws_ec2.NewFromConfig(cfg)
+	allRegions, _ := cmd.Flags().GetBool("all-regions")
+	allAccounts, _ := cmd.Flags().GetBool("all-accounts")
+	asg, _ := cmd.Flags().GetString("asg")
+	targetGroup, _ := cmd.Flags().GetString("target-group")
+	if asg != "" && targetGroup != "" {
+		return apperr.InvalidInput("--asg and --target-group cannot be used together")
+	}
 
+	var region string
 	var instanceID string
-	if prompt.HasRequiredFlags(cmd, requiredFlags) {
+	var cfg aws.Config
+	switch {
+	case prompt.HasRequiredFlags(cmd, requiredFlags):
+		region = cmd.Flag("region").Value.String()
 		instanceID = cmd.Flag("instance-id").Value.String()
-	} else {
-		instanceNameIDMap := ec2.GetInstanceNameIDMap(ec2Client)
-		displayNames := ec2.GetInstanceDisplayNames(instanceNameIDMap)
-		if len(displayNames) == 0 {
-			log.Fatalf("No EC2 instances found")
+		if cfg, err = config.LoadConfig(ctx, region, profile); err != nil {
+			return apperr.Wrap(err, "failed to load AWS config")
+		}
+	case asg != "" || targetGroup != "":
+		if region, err = getRegion(cmd, profile, prompter); err != nil {
+			return err
+		}
+		if cfg, err = config.LoadConfig(ctx, region, profile); err != nil {
+			return apperr.Wrap(err, "failed to load AWS config")
+		}
+		setScope(ctx, cfg, profile)
+
+		if instanceID, err = selectPoolInstance(cmd, cfg, prompter); err != nil {
+			return err
+		}
+	case allAccounts:
+		if region, err = getRegion(cmd, profile, prompter); err != nil {
+			return err
+		}
+		if cfg, instanceID, err = selectInstanceInAllAccounts(cmd, region, profile, prompter); err != nil {
+			return err
+		}
+	case allRegions:
+		if region, instanceID, err = selectInstanceInAllRegions(ctx, profile, prompter); err != nil {
+			return err
+		}
+		if cfg, err = config.LoadConfig(ctx, region, profile); err != nil {
+			return apperr.Wrap(err, "failed to load AWS config")
+		}
+	default:
+		if region, err = getRegion(cmd, profile, prompter); err != nil {
+			return err
+		}
+		if cfg, err = config.LoadConfig(ctx, region, profile); err != nil {
+			return apperr.Wrap(err, "failed to load AWS config")
+		}
+		setScope(ctx, cfg, profile)
+
+		if instanceID, err = getInstanceID(cmd, cfg, prompter); err != nil {
+			return err
+		}
+	}
+
+	entry := history.Entry{Type: settings.TargetTypeEC2, Profile: profile, InstanceID: instanceID}
+	if allAccounts {
+		entry.RoleName, _ = cmd.Flags().GetString("role-name")
+	}
+	if entry.Document, entry.Parameters, err = getSessionDocument(cmd, cfg, prompter); err != nil {
+		return err
+	}
+
+	// Credentials of an assumed member account cannot be reproduced with --profile.
+	if !allAccounts {
+		if !prompt.HasRequiredFlags(cmd, requiredFlags) {
+			printEcloginEc2WithOptionCommand(entry, region)
 		}
+		printAwsCliEc2Command(entry, region)
+	}
+
+	if err := ensureInstanceRunning(cmd, cfg, instanceID); err != nil {
+		return err
+	}
+	if stopOnExit, _ := cmd.Flags().GetBool("stop-on-exit"); stopOnExit {
+		defer stopInstance(ctx, cfg, instanceID)
+	}
+
+	started := time.Now()
+	if err := executeInstanceSession(ctx, cfg, entry); err != nil {
+		return apperr.Wrap(reconnectDropped(ctx, cfg, entry, started, err), "failed to execute instance session")
+	}
+	recordHistory(ctx, cfg, profile, entry)
+	return nil
+}
+
+// getInstanceID returns the --instance-id flag, or lets the user pick one of the instances in the region of cfg.
+func getInstanceID(cmd *cobra.Command, cfg aws.Config, prompter prompt.Prompter) (string, error) {
+	instances, err := cachedList(cmd, "instances", nil, func() ([]ec2.Instance, error) {
+		return ec2.ListInstances(cmd.Context(), aws_ec2.NewFromConfig(cfg))
+	})
+	if err != nil {
+		return "", apperr.Wrap(err, "failed to list EC2 instances")
+	}
 
-		selectedInstance := prompt.GetFlagOrSelect(cmd, "instance-id", "Select EC2 Instance", displayNames, prompter)
-		instanceID = instanceNameIDMap[selectedInstance]
-		printEcloginEc2WithOptionCommand(cmd, instanceID, region, profile)
+	instanceNameIDMap := ec2.NameIDMap(instances)
+	displayNames := ec2.GetInstanceDisplayNames(instanceNameIDMap)
+	if len(displayNames) == 0 {
+		return "", apperr.NotFound("no EC2 instances found")
 	}
-	printAwsCliEc2Command(cmd, instanceID, region, profile)
 
-	sessionInput := &ssm.StartSessionInput{Target: aws.String(instanceID)}
+	selectedInstance, err := prompt.GetFlagOrSelect(cmd, "instance-id", "Select EC2 Instance", displayNames, prompter)
+	if err != nil {
+		return "", err
+	}
+	return instanceNameIDMap[selectedInstance], nil
+}
+
+// ensureInstanceRunning starts the instance when it is stopped, after asking unless --start is given,
+// and waits until it is running and its SSM agent is online.
+func ensureInstanceRunning(cmd *cobra.Command, cfg aws.Config, instanceID string) error {
+	ctx := cmd.Context()
+	client := aws_ec2.NewFromConfig(cfg)
+	state, err := ec2.GetInstanceState(ctx, client, instanceID)
+	if err != nil {
+		return apperr.Wrap(err, "failed to get instance state")
+	}
+
+	switch state {
+	case instanceStateRunning:
+		return nil
+	case instanceStatePending:
+	case instanceStateStopped, instanceStateStopping:
+		if start, _ := cmd.Flags().GetBool("start"); !start {
+			if !prompt.Interactive() {
+				return apperr.InvalidInput("instance %s is %s, pass --start to start it", instanceID, state)
+			}
+			confirmed, err := confirm(fmt.Sprintf("Instance %s is %s. Start it?", instanceID, state))
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return apperr.ErrCancelled
+			}
+		}
+
+		if state == instanceStateStopping {
+			fmt.Printf("Waiting for %s to stop\n", instanceID)
+			if err := ec2.WaitUntilStopped(ctx, client, instanceID, instanceStateMaxWait); err != nil {
+				return apperr.Wrap(err, "failed to start instance")
+			}
+		}
+		fmt.Printf("Starting %s\n", instanceID)
+		if err := ec2.StartInstance(ctx, client, instanceID); err != nil {
+			return apperr.Wrap(err, "failed to start instance")
+		}
+	default:
+		return apperr.InvalidInput("instance %s is %s", instanceID, state)
+	}
+
+	fmt.Printf("Waiting for %s to run\n", instanceID)
+	if err := ec2.WaitUntilRunning(ctx, client, instanceID, instanceStateMaxWait); err != nil {
+		return apperr.Wrap(err, "failed to start instance")
+	}
+	fmt.Printf("Waiting for the SSM agent of %s to come online\n", instanceID)
+	if err := session.WaitForAgent(ctx, ssm.NewFromConfig(cfg), instanceID, agentPollInterval, agentMaxWait); err != nil {
+		return apperr.Wrap(err, "failed to start instance")
+	}
+	return nil
+}
+
+// stopInstance stops the instance once the session has ended, even when ctx has been cancelled
+// by a signal. Failures are only reported.
+func stopInstance(ctx context.Context, cfg aws.Config, instanceID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), terminateTimeout)
+	defer cancel()
+
+	fmt.Printf("Stopping %s\n", instanceID)
+	if err := ec2.StopInstance(ctx, aws_ec2.NewFromConfig(cfg), instanceID); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to stop instance: %v\n", err)
+	}
+}
+
+// executeInstanceSession starts an interactive session with the instance of entry, using its session document if any.
+func executeInstanceSession(ctx context.Context, cfg aws.Config, entry history.Entry) error {
+	sessionInput := &ssm.StartSessionInput{Target: aws.String(entry.InstanceID), Parameters: entry.Parameters}
+	if entry.Document != "" {
+		sessionInput.DocumentName = aws.String(entry.Document)
+	}
+	return startInstanceSession(ctx, cfg, sessionInput, true)
+}
+
+// getSessionDocument returns the session document given by --document-name or picked with --select-document,
+// and the parameters given by --parameters. An empty name means the default Session Manager document.
+func getSessionDocument(cmd *cobra.Command, cfg aws.Config, prompter prompt.Prompter) (string, map[string][]string, error) {
+	if command, _ := cmd.Flags().GetString("command"); command != "" {
+		for _, flag := range []string{"document-name", "parameters", "select-document"} {
+			if cmd.Flags().Changed(flag) {
+				return "", nil, apperr.InvalidInput("--command cannot be used with --%s", flag)
+			}
+		}
+		document, parameters := interactiveCommand(command)
+		return document, parameters, nil
+	}
+
+	document, _ := cmd.Flags().GetString("document-name")
+	pairs, _ := cmd.Flags().GetStringArray("parameters")
+	parameters, err := session.ParseParameters(pairs)
+	if err != nil {
+		return "", nil, apperr.InvalidInput("invalid --parameters: %v", err)
+	}
+
+	if selectDocument, _ := cmd.Flags().GetBool("select-document"); selectDocument && document == "" {
+		documents, err := cachedList(cmd, "documents", nil, func() ([]string, error) {
+			return session.ListSessionDocuments(cmd.Context(), ssm.NewFromConfig(cfg))
+		})
+		if err != nil {
+			return "", nil, apperr.Wrap(err, "failed to list session documents")
+		}
+		if len(documents) == 0 {
+			return "", nil, apperr.NotFound("no session documents found")
+		}
+		if document, err = prompter.Select("Select Session Document", documents); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if document == "" && len(parameters) > 0 {
+		return "", nil, apperr.InvalidInput("--parameters requires --document-name or --select-document")
+	}
+	return document, parameters, nil
+}
+
+// interactiveCommand returns the session document and parameters that run command on the instance instead of a shell.
+func interactiveCommand(command string) (string, map[string][]string) {
+	return interactiveCommandDocumentName, map[string][]string{"command": {command}}
+}
+
+// entryCommand returns the command of an entry that runs a command with interactiveCommand.
+func entryCommand(entry history.Entry) (string, bool) {
+	commands := entry.Parameters["command"]
+	if entry.Document != interactiveCommandDocumentName || len(entry.Parameters) != 1 || len(commands) != 1 {
+		return "", false
+	}
+	return commands[0], true
+}
+
+// startInstanceSession starts a session described by input and runs the session manager plugin with it.
+// Interactive sessions are recorded when recording is enabled; others, such as SSH proxies, never are.
+func startInstanceSession(ctx context.Context, cfg aws.Config, sessionInput *ssm.StartSessionInput, interactive bool) error {
+	instanceID := aws.ToString(sessionInput.Target)
 	ssmClient := ssm.NewFromConfig(cfg)
 
-	sessionOutput, err := ssmClient.StartSession(context.Background(), sessionInput)
+	sessionOutput, err := ssmClient.StartSession(ctx, sessionInput)
 	if err != nil {
-		log.Fatalf("Failed to start SSM session: %v", err)
+		return fmt.Errorf("start session failed: %w", err)
 	}
+	defer terminateSession(ctx, cfg, aws.ToString(sessionOutput.SessionId))
 
 	sessionData, err := json.Marshal(sessionOutput)
 	if err != nil {
-		log.Fatalf("Failed to marshal session data: %v", err)
+		return fmt.Errorf("marshal session failed: %w", err)
 	}
 
 	inputData, err := json.Marshal(sessionInput)
 	if err != nil {
-		log.Fatalf("Failed to marshal input data: %v", err)
+		return fmt.Errorf("marshal input failed: %w", err)
 	}
 
-	if err := session.StartSession(sessionData, inputData, region); err != nil {
-		log.Fatalf("Failed to start plugin session: %v", err)
+	endpoint, err := session.ResolveEndpoint(ctx, ssmClient)
+	if err != nil {
+		return err
 	}
+
+	var recorder *recording.Recorder
+	if interactive {
+		if recorder, err = startRecording(instanceID); err != nil {
+			return err
+		}
+		if recorder != nil {
+			defer recorder.Close()
+		}
+	}
+
+	endAudit := auditSession(ctx, &cfg, audit.Event{
+		Type:      settings.TargetTypeEC2,
+		Target:    instanceID,
+		SessionID: aws.ToString(sessionOutput.SessionId),
+	})
+	err = session.StartSession(ctx, sessionData, inputData, cfg.Region, endpoint, recorder)
+	endAudit(err)
+	return err
 }
 
-func printEcloginEc2WithOptionCommand(cmd *cobra.Command, instanceID string, region string, profile string) {
-	if !cmd.Flags().Changed("profile") {
-		fmt.Printf(`eclogin equivalent command:
-eclogin ec2 --instance-id %s --region %s
+// selectInstanceInAllRegions lists instances in every enabled region and returns the region and ID of the selected one.
+func selectInstanceInAllRegions(ctx context.Context, profile string, prompter prompt.Prompter) (string, string, error) {
+	instances, err := searchAllRegions(ctx, profile, func(region string) ([]ec2.Instance, error) {
+		cfg, err := config.LoadConfig(ctx, region, profile)
+		if err != nil {
+			return nil, err
+		}
+		return ec2.ListInstances(ctx, aws_ec2.NewFromConfig(cfg))
+	})
+	if err != nil {
+		return "", "", apperr.Wrap(err, "failed to list EC2 instances")
+	}
+	if len(instances) == 0 {
+		return "", "", apperr.NotFound("no EC2 instances found")
+	}
 
-`,
-			instanceID, region)
-	} else {
-		fmt.Printf(`eclogin equivalent command:
-eclogin ec2 --instance-id %s --region %s --profile %s
+	displayNames := make([]string, len(instances))
+	for i, instance := range instances {
+		displayNames[i] = fmt.Sprintf(regionColumnFormat, instance.Region, instance.Item.DisplayName())
+	}
 
-`,
-			instanceID, region, profile)
+	selected, err := prompter.Select("Select EC2 Instance", displayNames)
+	if err != nil {
+		return "", "", err
 	}
+	for i, displayName := range displayNames {
+		if displayName == selected {
+			return instances[i].Region, instances[i].Item.ID, nil
+		}
+	}
+	return "", "", apperr.NotFound("selected instance not found: %s", selected)
 }
 
-func printAwsCliEc2Command(cmd *cobra.Command, instanceID string, region string, profile string) {
-	if !cmd.Flags().Changed("profile") {
-		fmt.Printf(`If you are using awscli, please copy the following:
-aws ssm start-session \
-	--target %s \
-	--region %s
-
-`,
-			instanceID, region)
+func printEcloginEc2WithOptionCommand(entry history.Entry, region string) {
+	command := fmt.Sprintf("eclogin ec2 --instance-id %s --region %s", entry.InstanceID, region)
+	if entry.Profile != "" {
+		command += " --profile " + entry.Profile
+	}
+	if interactive, ok := entryCommand(entry); ok {
+		command += " --command " + shellQuote(interactive)
 	} else {
-		fmt.Printf(`If you are using awscli, please copy the following:
-aws ssm start-session \
-	--target %s \
-	--region %s \
-	--profile %s
+		if entry.Document != "" {
+			command += " --document-name " + shellQuote(entry.Document)
+		}
+		for _, pair := range session.FormatParameters(entry.Parameters) {
+			command += " --parameters " + shellQuote(pair)
+		}
+	}
+	fmt.Printf("eclogin equivalent command:\n%s\n\n", command)
+}
+
+func printAwsCliEc2Command(entry history.Entry, region string) {
+	options := []string{"--target " + entry.InstanceID, "--region " + region}
+	if entry.Profile != "" {
+		options = append(options, "--profile "+entry.Profile)
+	}
+	if entry.Document != "" {
+		options = append(options, "--document-name "+shellQuote(entry.Document))
+	}
+	if len(entry.Parameters) > 0 {
+		parameters, _ := json.Marshal(entry.Parameters)
+		options = append(options, "--parameters "+shellQuote(string(parameters)))
+	}
+	fmt.Printf("If you are using awscli, please copy the following:\naws ssm start-session \\\n\t%s\n\n", strings.Join(options, " \\\n\t"))
+}
 
-`,
-			instanceID, region, profile)
+// shellQuote quotes s for a POSIX shell when it contains anything but safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,:/=@%+") == "" {
+		return s
 	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
 }
 
 func init() {
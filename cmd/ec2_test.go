@@ -2,11 +2,11 @@ package cmd
 
 import (
 	"bytes"
+	"eclogin/pkg/history"
 	"io"
 	"os"
 	"testing"
 
-	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -14,19 +14,18 @@ type MockPrompter struct {
 	mock.Mock
 }
 
-func (m *MockPrompter) Input(message string, defaultValue string) string {
+func (m *MockPrompter) Input(message string, defaultValue string) (string, error) {
 	args := m.Called(message, defaultValue)
-	return args.String(0)
+	return args.String(0), args.Error(1)
 }
 
-func (m *MockPrompter) Select(message string, options []string) string {
+func (m *MockPrompter) Select(message string, options []string) (string, error) {
 	args := m.Called(message, options)
-	return args.String(0)
+	return args.String(0), args.Error(1)
 }
 
 func TestPrintAwsCliEc2Command(t *testing.T) {
 	tests := []struct {
-		cmd        *cobra.Command
 		name       string
 		instanceID string
 		region     string
@@ -34,7 +33,6 @@ func TestPrintAwsCliEc2Command(t *testing.T) {
 		expected   string
 	}{
 		{
-			cmd:        &cobra.Command{},
 			name:       "prints correct AWS CLI command",
 			instanceID: "i-1234567890abcdef0",
 			region:     "ap-northeast-1",
@@ -44,6 +42,19 @@ aws ssm start-session \
 	--target i-1234567890abcdef0 \
 	--region ap-northeast-1
 
+`,
+		},
+		{
+			name:       "prints the default profile",
+			instanceID: "i-1234567890abcdef0",
+			region:     "ap-northeast-1",
+			profile:    "dev",
+			expected: `If you are using awscli, please copy the following:
+aws ssm start-session \
+	--target i-1234567890abcdef0 \
+	--region ap-northeast-1 \
+	--profile dev
+
 `,
 		},
 	}
@@ -51,7 +62,7 @@ aws ssm start-session \
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := captureOutput(func() {
-				printAwsCliEc2Command(tt.cmd, tt.instanceID, tt.region, tt.profile)
+				printAwsCliEc2Command(history.Entry{InstanceID: tt.instanceID, Profile: tt.profile}, tt.region)
 			})
 			if result != tt.expected {
 				t.Errorf("expected %q, got %q", tt.expected, result)
@@ -60,6 +71,57 @@ aws ssm start-session \
 	}
 }
 
+func TestPrintEc2CommandsWithDocument(t *testing.T) {
+	entry := history.Entry{
+		InstanceID: "i-1234567890abcdef0",
+		Document:   "SessionAsAppUser",
+		Parameters: map[string][]string{"runAsUser": {"app"}, "logGroup": {"session logs"}},
+	}
+
+	result := captureOutput(func() {
+		printEcloginEc2WithOptionCommand(entry, "ap-northeast-1")
+		printAwsCliEc2Command(entry, "ap-northeast-1")
+	})
+	expected := `eclogin equivalent command:
+eclogin ec2 --instance-id i-1234567890abcdef0 --region ap-northeast-1 --document-name SessionAsAppUser --parameters 'logGroup=session logs' --parameters runAsUser=app
+
+If you are using awscli, please copy the following:
+aws ssm start-session \
+	--target i-1234567890abcdef0 \
+	--region ap-northeast-1 \
+	--document-name SessionAsAppUser \
+	--parameters '{"logGroup":["session logs"],"runAsUser":["app"]}'
+
+`
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestPrintEc2CommandsWithCommand(t *testing.T) {
+	document, parameters := interactiveCommand("sudo su - app")
+	entry := history.Entry{InstanceID: "i-1234567890abcdef0", Document: document, Parameters: parameters}
+
+	result := captureOutput(func() {
+		printEcloginEc2WithOptionCommand(entry, "ap-northeast-1")
+		printAwsCliEc2Command(entry, "ap-northeast-1")
+	})
+	expected := `eclogin equivalent command:
+eclogin ec2 --instance-id i-1234567890abcdef0 --region ap-northeast-1 --command 'sudo su - app'
+
+If you are using awscli, please copy the following:
+aws ssm start-session \
+	--target i-1234567890abcdef0 \
+	--region ap-northeast-1 \
+	--document-name AWS-StartInteractiveCommand \
+	--parameters '{"command":["sudo su - app"]}'
+
+`
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
 func captureOutput(f func()) string {
 	old := os.Stdout
 	r, w, _ := os.Pipe()
@@ -75,3 +137,28 @@ func captureOutput(f func()) string {
 
 	return buf.String()
 }
+
+func TestProxyCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  string
+		flags    []string
+		expected string
+	}{
+		{name: "region only", expected: "eclogin ec2 proxy %h %p --region ap-northeast-1"},
+		{name: "profile", profile: "dev", expected: "eclogin ec2 proxy %h %p --region ap-northeast-1 --profile dev"},
+		{
+			name:     "forwarded flags",
+			flags:    []string{"--endpoint-url http://localhost:4566", "--timeout 1m0s"},
+			expected: "eclogin ec2 proxy %h %p --region ap-northeast-1 --endpoint-url http://localhost:4566 --timeout 1m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proxyCommand("eclogin", "ap-northeast-1", tt.profile, tt.flags); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
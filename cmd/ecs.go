@@ -2,14 +2,19 @@ package cmd
 
 import (
 	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/audit"
 	"eclogin/pkg/aws/config"
 	"eclogin/pkg/aws/ecs"
 	"eclogin/pkg/aws/session"
+	"eclogin/pkg/history"
 	"eclogin/pkg/prompt"
+	"eclogin/pkg/settings"
 	"encoding/json"
 	"fmt"
-	"log"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	aws_ecs "github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/spf13/cobra"
@@ -24,8 +29,7 @@ type ECSClientInterface interface {
 }
 
 const (
-	defaultRegion = "ap-northeast-1"
-	targetFormat  = "ecs:%s_%s_%s"
+	targetFormat = "ecs:%s_%s_%s"
 )
 
 var (
@@ -38,102 +42,211 @@ var ecsCmd = &cobra.Command{
 	Long: `The ecs command allows you to start an interactive session with an ECS container
 using ECS Exec. You can select a cluster, service, task, and container,
 and establish a session to manage it remotely.`,
-	Run: runECSCommand,
+	RunE: runECSCommand,
 }
 
-func runECSCommand(cmd *cobra.Command, _ []string) {
+func runECSCommand(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
 	requiredFlags := []string{"cluster", "task-id", "container", "shell", "region"}
-	prompter := prompt.NewUIPrompter()
-	region := prompt.GetFlagOrInput(cmd, "region", "Please enter AWS region", defaultRegion, prompter)
+	prompter := newPrompter()
 
-	var profile string
-	if prompt.HasRequiredFlags(cmd, requiredFlags) {
-		profile = cmd.Flag("profile").Value.String()
-	} else {
-		profile = prompt.GetFlagOrInput(cmd, "profile", "Please enter AWS profile (optional)", "", prompter)
+	profile, err := getProfile(cmd, requiredFlags, prompter)
+	if err != nil {
+		return err
 	}
 
-	cfg, err := config.LoadConfig(region, profile)
-	if err != nil {
-		log.Fatalf("Failed to load AWS config: %v", err)
+	allRegions, _ := cmd.Flags().GetBool("all-regions")
+	allAccounts, _ := cmd.Flags().GetBool("all-accounts")
+
+	var region string
+	var cluster string
+	var service string
+	var cfg aws.Config
+	switch {
+	case allAccounts && !prompt.HasRequiredFlags(cmd, requiredFlags):
+		if region, err = getRegion(cmd, profile, prompter); err != nil {
+			return err
+		}
+		if cfg, cluster, service, err = selectServiceInAllAccounts(cmd, region, profile, prompter); err != nil {
+			return err
+		}
+	case allRegions && !cmd.Flags().Changed("cluster"):
+		if region, cluster, err = selectClusterInAllRegions(ctx, profile, prompter); err != nil {
+			return err
+		}
+		if cfg, err = config.LoadConfig(ctx, region, profile); err != nil {
+			return apperr.Wrap(err, "failed to load AWS config")
+		}
+	default:
+		if region, err = getRegion(cmd, profile, prompter); err != nil {
+			return err
+		}
+		if cfg, err = config.LoadConfig(ctx, region, profile); err != nil {
+			return apperr.Wrap(err, "failed to load AWS config")
+		}
 	}
 
+	assumed := allAccounts && !prompt.HasRequiredFlags(cmd, requiredFlags)
+	if assumed {
+		roleName, _ := cmd.Flags().GetString("role-name")
+		setAssumedScope(ctx, cfg, roleName)
+	} else {
+		setScope(ctx, cfg, profile)
+	}
 	ecsClient := aws_ecs.NewFromConfig(cfg)
 
-	cluster, err := getECSCluster(cmd, ecsClient)
-	if err != nil {
-		log.Fatalf("Failed to get ECS cluster: %v", err)
+	if cluster == "" {
+		cluster, err = getECSCluster(cmd, ecsClient, prompter)
+		if err != nil {
+			return apperr.Wrap(err, "failed to get ECS cluster")
+		}
 	}
 
-	var service string
 	var taskID string
 	var containerInfo map[string]string
 	if prompt.HasRequiredFlags(cmd, requiredFlags) {
 		taskID = cmd.Flag("task-id").Value.String()
 	} else {
-		service, err = getECSService(cmd, ecsClient, cluster)
-		if err != nil {
-			log.Fatalf("Failed to get ECS service: %v", err)
+		if service == "" {
+			service, err = getECSService(cmd, ecsClient, cluster, prompter)
+			if err != nil {
+				return apperr.Wrap(err, "failed to get ECS service")
+			}
 		}
 
-		taskID, err = getECSTaskID(cmd, ecsClient, cluster, service)
+		taskID, err = getECSTaskID(cmd, ecsClient, cluster, service, prompter)
 		if err != nil {
-			log.Fatalf("Failed to get ECS task ID: %v", err)
+			return apperr.Wrap(err, "failed to get ECS task ID")
 		}
 
-		containerInfo, err = ecs.GetContainerInfo(ecsClient, cluster, taskID)
+		containerInfo, err = ecs.GetContainerInfo(ctx, ecsClient, cluster, taskID)
 		if err != nil {
-			log.Fatalf("Failed to get container information: %v", err)
+			return apperr.Wrap(err, "failed to get container information")
 		}
 	}
 
-	container, runtimeID := selectContainer(cmd, containerInfo)
-	shell := prompt.GetFlagOrSelect(cmd, "shell", "Select Shell", availableShells, prompt.NewUIPrompter())
-
-	if prompt.HasRequiredFlags(cmd, requiredFlags) {
-	} else {
-		printEcloginEcsWithOptionCommand(cmd, cluster, taskID, container, shell, region, profile)
+	container, runtimeID, err := selectContainer(cmd, containerInfo, prompter)
+	if err != nil {
+		return err
+	}
+	shell, err := getShell(cmd, prompter)
+	if err != nil {
+		return err
 	}
 
-	printAwsCliEcsCommand(cluster, taskID, container, shell, region, profile)
+	// Credentials of an assumed member account cannot be reproduced with --profile.
+	if !allAccounts {
+		if prompt.HasRequiredFlags(cmd, requiredFlags) {
+		} else {
+			printEcloginEcsWithOptionCommand(cluster, taskID, container, shell, region, profile)
+		}
 
-	if err := executeContainerSession(ecsClient, shell, taskID, cluster, container, runtimeID, region); err != nil {
-		log.Fatalf("Failed to execute container session: %v", err)
+		printAwsCliEcsCommand(cluster, taskID, container, shell, region, profile)
+	}
+
+	entry := history.Entry{
+		Type:      settings.TargetTypeECS,
+		Profile:   profile,
+		Cluster:   cluster,
+		Service:   service,
+		TaskID:    taskID,
+		Container: container,
+		Shell:     shell,
+	}
+	if assumed {
+		entry.RoleName, _ = cmd.Flags().GetString("role-name")
 	}
+	started := time.Now()
+	if err := executeContainerSession(ctx, cfg, shell, taskID, cluster, container, runtimeID); err != nil {
+		return apperr.Wrap(reconnectDropped(ctx, cfg, entry, started, err), "failed to execute container session")
+	}
+	recordHistory(ctx, cfg, profile, entry)
+	return nil
 }
 
-func getECSCluster(cmd *cobra.Command, client ECSClientInterface) (string, error) {
-	clusters, err := ecs.ListClusters(client)
+func getECSCluster(cmd *cobra.Command, client ECSClientInterface, prompter prompt.Prompter) (string, error) {
+	clusters, err := cachedList(cmd, "clusters", nil, func() ([]string, error) {
+		return ecs.ListClusters(cmd.Context(), client)
+	})
 	if err != nil {
 		return "", err
 	}
-	return prompt.GetFlagOrSelect(cmd, "cluster", "Select ECS Cluster", clusters, prompt.NewUIPrompter()), nil
+	if len(clusters) == 0 && cmd.Flag("cluster").Value.String() == "" {
+		return "", apperr.NotFound("no clusters found")
+	}
+	return prompt.GetFlagOrSelect(cmd, "cluster", "Select ECS Cluster", clusters, prompter)
+}
+
+// selectClusterInAllRegions lists clusters in every enabled region and returns the region and name of the selected one.
+func selectClusterInAllRegions(ctx context.Context, profile string, prompter prompt.Prompter) (string, string, error) {
+	clusters, err := searchAllRegions(ctx, profile, func(region string) ([]string, error) {
+		cfg, err := config.LoadConfig(ctx, region, profile)
+		if err != nil {
+			return nil, err
+		}
+		return ecs.ListClusters(ctx, aws_ecs.NewFromConfig(cfg))
+	})
+	if err != nil {
+		return "", "", apperr.Wrap(err, "failed to list ECS clusters")
+	}
+	if len(clusters) == 0 {
+		return "", "", apperr.NotFound("no ECS clusters found")
+	}
+
+	displayNames := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		displayNames[i] = fmt.Sprintf(regionColumnFormat, cluster.Region, cluster.Item)
+	}
+
+	selected, err := prompter.Select("Select ECS Cluster", displayNames)
+	if err != nil {
+		return "", "", err
+	}
+	for i, displayName := range displayNames {
+		if displayName == selected {
+			return clusters[i].Region, clusters[i].Item, nil
+		}
+	}
+	return "", "", apperr.NotFound("selected cluster not found: %s", selected)
 }
 
-func getECSService(cmd *cobra.Command, client ECSClientInterface, cluster string) (string, error) {
-	services, err := ecs.ListServices(client, cluster)
+func getECSService(cmd *cobra.Command, client ECSClientInterface, cluster string, prompter prompt.Prompter) (string, error) {
+	services, err := cachedList(cmd, "services", []string{cluster}, func() ([]string, error) {
+		return ecs.ListServices(cmd.Context(), client, cluster)
+	})
 	if err != nil {
 		return "", err
 	}
-	return prompt.GetFlagOrSelect(cmd, "service", "Select ECS Service", services, prompt.NewUIPrompter()), nil
+	if len(services) == 0 && cmd.Flag("service").Value.String() == "" {
+		return "", apperr.NotFound("no services found in cluster %s", cluster)
+	}
+	return prompt.GetFlagOrSelect(cmd, "service", "Select ECS Service", services, prompter)
 }
 
-func getECSTaskID(cmd *cobra.Command, client ECSClientInterface, cluster, service string) (string, error) {
-	taskIDs, err := ecs.ListTaskIDs(client, cluster, service)
+func getECSTaskID(cmd *cobra.Command, client ECSClientInterface, cluster, service string, prompter prompt.Prompter) (string, error) {
+	taskIDs, err := cachedList(cmd, "tasks", []string{cluster, service}, func() ([]string, error) {
+		return ecs.ListTaskIDs(cmd.Context(), client, cluster, service)
+	})
 	if err != nil {
 		return "", err
 	}
-	return prompt.GetFlagOrSelect(cmd, "task-id", "Select ECS Task ID", taskIDs, prompt.NewUIPrompter()), nil
+	if len(taskIDs) == 0 && cmd.Flag("task-id").Value.String() == "" {
+		return "", apperr.NotFound("no tasks found for service %s in cluster %s", service, cluster)
+	}
+	return prompt.GetFlagOrSelect(cmd, "task-id", "Select ECS Task ID", taskIDs, prompter)
 }
 
-func selectContainer(cmd *cobra.Command, containerInfo map[string]string) (string, string) {
+func selectContainer(cmd *cobra.Command, containerInfo map[string]string, prompter prompt.Prompter) (string, string, error) {
 	containers := ecs.ListContainerNames(containerInfo)
-	container := prompt.GetFlagOrSelect(cmd, "container", "Select ECS Container", containers, prompt.NewUIPrompter())
-	return container, containerInfo[container]
+	container, err := prompt.GetFlagOrSelect(cmd, "container", "Select ECS Container", containers, prompter)
+	if err != nil {
+		return "", "", err
+	}
+	return container, containerInfo[container], nil
 }
 
-func printEcloginEcsWithOptionCommand(cmd *cobra.Command, cluster string, taskID string, container string, shell string, region string, profile string) {
-	if !cmd.Flags().Changed("profile") {
+func printEcloginEcsWithOptionCommand(cluster string, taskID string, container string, shell string, region string, profile string) {
+	if profile == "" {
 		fmt.Printf(`eclogin equivalent command:
 eclogin ecs --cluster %s --task-id %s --container %s --shell %s --region %s
 
@@ -177,11 +290,12 @@ aws ecs execute-command \
 	}
 }
 
-func executeContainerSession(client *aws_ecs.Client, shell, taskID, cluster, container, runtimeID, region string) error {
-	out, err := ecs.ExecuteContainerCommand(client, shell, taskID, cluster, container)
+func executeContainerSession(ctx context.Context, cfg aws.Config, shell, taskID, cluster, container, runtimeID string) error {
+	out, err := ecs.ExecuteContainerCommand(ctx, aws_ecs.NewFromConfig(cfg), shell, taskID, cluster, container)
 	if err != nil {
 		return fmt.Errorf("execute command failed: %w", err)
 	}
+	defer terminateSession(ctx, cfg, aws.ToString(out.Session.SessionId))
 
 	sessionJSON, err := json.Marshal(out.Session)
 	if err != nil {
@@ -198,7 +312,27 @@ func executeContainerSession(client *aws_ecs.Client, shell, taskID, cluster, con
 		return fmt.Errorf("marshal input failed: %w", err)
 	}
 
-	return session.StartSession(sessionJSON, inputJSON, region)
+	endpoint, err := session.ResolveEndpoint(ctx, ssm.NewFromConfig(cfg))
+	if err != nil {
+		return err
+	}
+
+	recorder, err := startRecording(fmt.Sprintf("%s/%s/%s", cluster, taskID, container))
+	if err != nil {
+		return err
+	}
+	if recorder != nil {
+		defer recorder.Close()
+	}
+
+	endAudit := auditSession(ctx, &cfg, audit.Event{
+		Type:      settings.TargetTypeECS,
+		Target:    fmt.Sprintf("%s/%s/%s", cluster, taskID, container),
+		SessionID: aws.ToString(out.Session.SessionId),
+	})
+	err = session.StartSession(ctx, sessionJSON, inputJSON, cfg.Region, endpoint, recorder)
+	endAudit(err)
+	return err
 }
 
 func init() {
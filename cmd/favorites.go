@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"eclogin/pkg/favorites"
+	"eclogin/pkg/prompt"
+	"eclogin/pkg/settings"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var favoritesStore *favorites.Store
+
+// newPrompter returns a prompter whose pickers list favorites and recently used options first.
+// It falls back to a plain prompter when the favorites cannot be loaded.
+func newPrompter() prompt.Prompter {
+	if favoritesStore == nil {
+		store, err := openFavorites()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load favorites: %v\n", err)
+			return prompt.NewUIPrompter()
+		}
+		favoritesStore = store
+	}
+	return prompt.NewRankedPrompter(favoritesStore)
+}
+
+func openFavorites() (*favorites.Store, error) {
+	dir, err := settings.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return favorites.Open(filepath.Join(dir, "favorites.json"))
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/aws/config"
+	"eclogin/pkg/aws/ecs"
+	"eclogin/pkg/aws/sts"
+	"eclogin/pkg/history"
+	"eclogin/pkg/settings"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_ecs "github.com/aws/aws-sdk-go-v2/service/ecs"
+	aws_sts "github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+const historyTimeFormat = "2006-01-02 15:04:05"
+
+var historyCmd = &cobra.Command{
+	Use:   "history [query]",
+	Short: "List previous connections",
+	Long: `The history command lists previous connections, newest first.
+If a query is given, only connections with a field containing it are listed.
+With --select, the list is shown as a picker and the selected connection is reopened.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHistoryCommand,
+}
+
+var lastCmd = &cobra.Command{
+	Use:   "last",
+	Short: "Reconnect to the most recent connection",
+	Long: `The last command reconnects to the most recent connection.
+For ECS, a running task of the same service is picked when the previous task has stopped.`,
+	Args: cobra.NoArgs,
+	RunE: runLastCommand,
+}
+
+func historyStore() (*history.Store, error) {
+	dir, err := settings.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return history.NewStore(filepath.Join(dir, "history.jsonl")), nil
+}
+
+// recordHistory appends a successful connection to the history. Failures are only reported,
+// since they should not turn a successful session into an error.
+func recordHistory(ctx context.Context, cfg aws.Config, profile string, entry history.Entry) {
+	entry.Region = cfg.Region
+	entry.Profile = profile
+	entry.Timestamp = time.Now()
+	if identity, err := sts.GetCallerIdentity(ctx, aws_sts.NewFromConfig(cfg)); err == nil {
+		entry.Account = identity.Account
+	}
+	if entry.RoleName != "" && entry.Account == "" {
+		fmt.Fprintln(os.Stderr, "Failed to record history: the account of the assumed role is unknown")
+		return
+	}
+
+	store, err := historyStore()
+	if err == nil {
+		err = store.Append(entry)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record history: %v\n", err)
+	}
+}
+
+func runHistoryCommand(cmd *cobra.Command, args []string) error {
+	store, err := historyStore()
+	if err != nil {
+		return apperr.Wrap(err, "failed to open history")
+	}
+
+	entries, err := store.Load()
+	if err != nil {
+		return apperr.Wrap(err, "failed to load history")
+	}
+
+	var matched []history.Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if len(args) == 0 || entries[i].Matches(args[0]) {
+			matched = append(matched, entries[i])
+		}
+	}
+	if len(matched) == 0 {
+		return apperr.NotFound("no connection history found")
+	}
+
+	if selectEntry, _ := cmd.Flags().GetBool("select"); selectEntry {
+		displayNames := make([]string, len(matched))
+		for i, entry := range matched {
+			displayNames[i] = formatHistoryEntry(entry)
+		}
+
+		selected, err := newPrompter().Select("Select Connection", displayNames)
+		if err != nil {
+			return err
+		}
+		for i, displayName := range displayNames {
+			if displayName == selected {
+				return reconnect(cmd.Context(), matched[i])
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tTYPE\tACCOUNT\tREGION\tPROFILE\tTARGET")
+	for _, entry := range matched {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Timestamp.Local().Format(historyTimeFormat), entry.Type, entry.Account, entry.Region, entry.Profile, entry.Target())
+	}
+	return w.Flush()
+}
+
+func formatHistoryEntry(entry history.Entry) string {
+	return fmt.Sprintf("%s %-3s %-12s %-16s %s",
+		entry.Timestamp.Local().Format(historyTimeFormat), entry.Type, entry.Account, entry.Region, entry.Target())
+}
+
+func runLastCommand(cmd *cobra.Command, _ []string) error {
+	store, err := historyStore()
+	if err != nil {
+		return apperr.Wrap(err, "failed to open history")
+	}
+
+	entry, err := store.Last()
+	if err != nil {
+		return apperr.Wrap(err, "failed to load history")
+	}
+
+	return reconnect(cmd.Context(), entry)
+}
+
+// reconnect opens a session with the target of a history entry. For ECS, a fresh task
+// of the same service is picked when the recorded task is no longer running.
+func reconnect(ctx context.Context, entry history.Entry) error {
+	cfg, err := config.LoadConfig(ctx, entry.Region, entry.Profile)
+	if err != nil {
+		return apperr.Wrap(err, "failed to load AWS config")
+	}
+	if entry.RoleName != "" {
+		cfg = config.AssumeRoleConfig(cfg, entry.Account, entry.RoleName)
+	}
+
+	started := time.Now()
+	return reconnectDropped(ctx, cfg, entry, started, reopen(ctx, cfg, entry))
+}
+
+// reopen opens a session with the target of a history entry using cfg, and records it in the history.
+func reopen(ctx context.Context, cfg aws.Config, entry history.Entry) error {
+	profile := entry.Profile
+	switch entry.Type {
+	case settings.TargetTypeEC2:
+		fmt.Printf("Connecting to %s\n\n", entry.InstanceID)
+		if err := executeInstanceSession(ctx, cfg, entry); err != nil {
+			return apperr.Wrap(err, "failed to execute instance session")
+		}
+	case settings.TargetTypeECS:
+		client := aws_ecs.NewFromConfig(cfg)
+		running, err := ecs.IsTaskRunning(ctx, client, entry.Cluster, entry.TaskID)
+		if err != nil {
+			return apperr.Wrap(err, "failed to describe ECS task")
+		}
+
+		if running {
+			containerInfo, err := ecs.GetContainerInfo(ctx, client, entry.Cluster, entry.TaskID)
+			if err != nil {
+				return apperr.Wrap(err, "failed to get container information")
+			}
+
+			runtimeID, ok := containerInfo[entry.Container]
+			if !ok {
+				return apperr.NotFound("container %s not found in task %s", entry.Container, entry.TaskID)
+			}
+
+			fmt.Printf("Connecting to %s task %s container %s\n\n", entry.Cluster, entry.TaskID, entry.Container)
+			if err := executeContainerSession(ctx, cfg, entry.Shell, entry.TaskID, entry.Cluster, entry.Container, runtimeID); err != nil {
+				return apperr.Wrap(err, "failed to execute container session")
+			}
+		} else {
+			if entry.Service == "" {
+				return apperr.NotFound("task %s is no longer running and no service was recorded", entry.TaskID)
+			}
+
+			fmt.Printf("Task %s is no longer running, picking a new task of %s\n", entry.TaskID, entry.Service)
+			roleName := entry.RoleName
+			entry, err = connectECSAlias(ctx, cfg, settings.Alias{
+				Cluster:   entry.Cluster,
+				Service:   entry.Service,
+				Container: entry.Container,
+				Shell:     entry.Shell,
+			}, newPrompter())
+			if err != nil {
+				return apperr.Wrap(err, "failed to execute container session")
+			}
+			entry.RoleName = roleName
+		}
+	default:
+		return apperr.InvalidInput("unknown connection type: %s", entry.Type)
+	}
+
+	recordHistory(ctx, cfg, profile, entry)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(lastCmd)
+}
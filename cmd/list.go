@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/aws/config"
+	"eclogin/pkg/aws/ec2"
+	"eclogin/pkg/aws/ecs"
+	"eclogin/pkg/output"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	aws_ecs "github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/spf13/cobra"
+)
+
+type instanceRecord struct {
+	InstanceID string `json:"instance_id" yaml:"instance_id"`
+	Name       string `json:"name" yaml:"name"`
+	State      string `json:"state" yaml:"state"`
+	Region     string `json:"region" yaml:"region"`
+}
+
+type clusterRecord struct {
+	Cluster string `json:"cluster" yaml:"cluster"`
+	Region  string `json:"region" yaml:"region"`
+}
+
+type serviceRecord struct {
+	Cluster string `json:"cluster" yaml:"cluster"`
+	Service string `json:"service" yaml:"service"`
+}
+
+type taskRecord struct {
+	Cluster string `json:"cluster" yaml:"cluster"`
+	Service string `json:"service" yaml:"service"`
+	TaskID  string `json:"task_id" yaml:"task_id"`
+}
+
+type containerRecord struct {
+	Cluster   string `json:"cluster" yaml:"cluster"`
+	TaskID    string `json:"task_id" yaml:"task_id"`
+	Container string `json:"container" yaml:"container"`
+	RuntimeID string `json:"runtime_id" yaml:"runtime_id"`
+}
+
+var ec2ListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List EC2 instances",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, err := loadListConfig(cmd)
+		if err != nil {
+			return err
+		}
+		instances, err := ec2.ListInstances(cmd.Context(), aws_ec2.NewFromConfig(cfg))
+		if err != nil {
+			return apperr.Wrap(err, "failed to list EC2 instances")
+		}
+
+		records := make([]instanceRecord, len(instances))
+		for i, instance := range instances {
+			records[i] = instanceRecord{InstanceID: instance.ID, Name: instance.Name, State: instance.State, Region: cfg.Region}
+		}
+		return writeRecords(cmd, records)
+	},
+}
+
+var ecsClustersCmd = &cobra.Command{
+	Use:   "clusters",
+	Short: "List ECS clusters",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, err := loadListConfig(cmd)
+		if err != nil {
+			return err
+		}
+		clusters, err := ecs.ListClusters(cmd.Context(), aws_ecs.NewFromConfig(cfg))
+		if err != nil {
+			return apperr.Wrap(err, "failed to list ECS clusters")
+		}
+
+		records := make([]clusterRecord, len(clusters))
+		for i, cluster := range clusters {
+			records[i] = clusterRecord{Cluster: cluster, Region: cfg.Region}
+		}
+		return writeRecords(cmd, records)
+	},
+}
+
+var ecsServicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "List the services of an ECS cluster",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cluster, err := requiredFlag(cmd, "cluster")
+		if err != nil {
+			return err
+		}
+		cfg, err := loadListConfig(cmd)
+		if err != nil {
+			return err
+		}
+		services, err := ecs.ListServices(cmd.Context(), aws_ecs.NewFromConfig(cfg), cluster)
+		if err != nil {
+			return apperr.Wrap(err, "failed to list ECS services")
+		}
+
+		records := make([]serviceRecord, len(services))
+		for i, service := range services {
+			records[i] = serviceRecord{Cluster: cluster, Service: service}
+		}
+		return writeRecords(cmd, records)
+	},
+}
+
+var ecsTasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "List the running tasks of an ECS service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cluster, err := requiredFlag(cmd, "cluster")
+		if err != nil {
+			return err
+		}
+		service, err := requiredFlag(cmd, "service")
+		if err != nil {
+			return err
+		}
+		cfg, err := loadListConfig(cmd)
+		if err != nil {
+			return err
+		}
+		taskIDs, err := ecs.ListTaskIDs(cmd.Context(), aws_ecs.NewFromConfig(cfg), cluster, service)
+		if err != nil {
+			return apperr.Wrap(err, "failed to list ECS tasks")
+		}
+
+		records := make([]taskRecord, len(taskIDs))
+		for i, taskID := range taskIDs {
+			records[i] = taskRecord{Cluster: cluster, Service: service, TaskID: taskID}
+		}
+		return writeRecords(cmd, records)
+	},
+}
+
+var ecsContainersCmd = &cobra.Command{
+	Use:   "containers",
+	Short: "List the containers of an ECS task",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cluster, err := requiredFlag(cmd, "cluster")
+		if err != nil {
+			return err
+		}
+		taskID, err := requiredFlag(cmd, "task-id")
+		if err != nil {
+			return err
+		}
+		cfg, err := loadListConfig(cmd)
+		if err != nil {
+			return err
+		}
+		containerInfo, err := ecs.GetContainerInfo(cmd.Context(), aws_ecs.NewFromConfig(cfg), cluster, taskID)
+		if err != nil {
+			return apperr.Wrap(err, "failed to get container information")
+		}
+
+		containers := ecs.ListContainerNames(containerInfo)
+		sort.Strings(containers)
+		records := make([]containerRecord, len(containers))
+		for i, container := range containers {
+			records[i] = containerRecord{Cluster: cluster, TaskID: taskID, Container: container, RuntimeID: containerInfo[container]}
+		}
+		return writeRecords(cmd, records)
+	},
+}
+
+var localListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List running local Docker containers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		executor, err := newDockerExecutor(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to connect to Docker: %w", err)
+		}
+
+		containers, err := executor.listRunningContainers()
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+		return writeRecords(cmd, containers)
+	},
+}
+
+// loadListConfig loads the AWS config from --region and --profile without prompting,
+// falling back to the defaults of the settings file.
+func loadListConfig(cmd *cobra.Command) (aws.Config, error) {
+	region, _ := cmd.Flags().GetString("region")
+	if region == "" {
+		region = preferredRegion()
+	}
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile == "" {
+		profile = userSettings.DefaultProfile
+	}
+
+	cfg, err := config.LoadConfig(cmd.Context(), region, profile)
+	if err != nil {
+		return aws.Config{}, apperr.Wrap(err, "failed to load AWS config")
+	}
+	return cfg, nil
+}
+
+func requiredFlag(cmd *cobra.Command, name string) (string, error) {
+	value, _ := cmd.Flags().GetString(name)
+	if value == "" {
+		return "", apperr.InvalidInput("--%s is required", name)
+	}
+	return value, nil
+}
+
+func writeRecords[T any](cmd *cobra.Command, records []T) error {
+	format, _ := cmd.Flags().GetString("output")
+	if err := output.Write(os.Stdout, format, records); err != nil {
+		return apperr.Wrap(err, "failed to write output")
+	}
+	return nil
+}
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", output.FormatTable, fmt.Sprintf("Output format (%s)", strings.Join(output.Formats, "|")))
+}
+
+func init() {
+	ec2Cmd.AddCommand(ec2ListCmd)
+	ecsCmd.AddCommand(ecsClustersCmd, ecsServicesCmd, ecsTasksCmd, ecsContainersCmd)
+	localCmd.AddCommand(localListCmd)
+}
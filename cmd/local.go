@@ -2,9 +2,16 @@ package cmd
 
 import (
 	"context"
-	"fmt"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/audit"
+	"eclogin/pkg/prompt"
+	"eclogin/pkg/recording"
+	"eclogin/pkg/settings"
+	"eclogin/pkg/transfer"
+	"errors"
 	"io"
 	"os"
+	pathpkg "path"
 	"strings"
 
 	"github.com/docker/docker/api/types"
@@ -36,13 +43,40 @@ func newDockerExecutor(ctx context.Context) (*dockerExecutor, error) {
 	return &dockerExecutor{client: cli, ctx: ctx}, nil
 }
 
-func (d *dockerExecutor) getRunningContainers() (map[string]string, []string, error) {
+// localContainer is a running Docker container, one per container name.
+type localContainer struct {
+	Name        string `json:"name" yaml:"name"`
+	Image       string `json:"image" yaml:"image"`
+	ContainerID string `json:"container_id" yaml:"container_id"`
+}
+
+func (d *dockerExecutor) listRunningContainers() ([]localContainer, error) {
 	containerFilter := filters.NewArgs(filters.KeyValuePair{
 		Key:   "status",
 		Value: "running",
 	})
 
 	containers, err := d.client.ContainerList(d.ctx, container.ListOptions{Filters: containerFilter})
+	if err != nil {
+		return nil, err
+	}
+
+	var localContainers []localContainer
+	for _, container := range containers {
+		for _, name := range container.Names {
+			localContainers = append(localContainers, localContainer{
+				Name:        strings.TrimLeft(name, "/"),
+				Image:       container.Image,
+				ContainerID: container.ID,
+			})
+		}
+	}
+
+	return localContainers, nil
+}
+
+func (d *dockerExecutor) getRunningContainers() (map[string]string, []string, error) {
+	containers, err := d.listRunningContainers()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -51,11 +85,9 @@ func (d *dockerExecutor) getRunningContainers() (map[string]string, []string, er
 	var containerNames []string
 
 	for _, container := range containers {
-		for _, name := range container.Names {
-			displayName := formatContainerName(name, container.Image)
-			containerMap[displayName] = container.ID
-			containerNames = append(containerNames, displayName)
-		}
+		displayName := formatContainerName(container.Name, container.Image)
+		containerMap[displayName] = container.ContainerID
+		containerNames = append(containerNames, displayName)
 	}
 
 	return containerMap, containerNames, nil
@@ -65,16 +97,26 @@ func formatContainerName(name, image string) string {
 	return strings.TrimLeft(name, "/") + "(" + image + ")"
 }
 
-func selectOption(prompt string, options []string) (string, error) {
+func selectOption(label string, options []string) (string, error) {
+	if !prompt.Interactive() {
+		if len(options) == 1 {
+			return options[0], nil
+		}
+		return "", &apperr.Error{Kind: apperr.KindInvalidInput, Err: &prompt.MissingInputError{Label: label, Candidates: options}}
+	}
+
 	selector := promptui.Select{
-		Label: prompt,
+		Label: label,
 		Items: options,
 	}
 	_, result, err := selector.Run()
+	if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+		return "", apperr.ErrCancelled
+	}
 	return result, err
 }
 
-func (d *dockerExecutor) executeInContainer(containerID, shell string) error {
+func (d *dockerExecutor) executeInContainer(containerID, shell string, recorder *recording.Recorder) error {
 	execConfig := container.ExecOptions{
 		AttachStdin:  true,
 		AttachStdout: true,
@@ -94,9 +136,44 @@ func (d *dockerExecutor) executeInContainer(containerID, shell string) error {
 	}
 	defer execConn.Close()
 
-	return setupTerminal(execConn)
+	if err := setupTerminal(execConn, recorder); err != nil {
+		return err
+	}
+
+	inspect, err := d.client.ContainerExecInspect(d.ctx, execResp.ID)
+	if err != nil {
+		return err
+	}
+	if inspect.ExitCode != 0 {
+		return &audit.ExitStatusError{Code: inspect.ExitCode}
+	}
+	return nil
 }
-func setupTerminal(execConn types.HijackedResponse) error {
+
+// copyToContainer writes content to path in the container, which is a name or an ID.
+func (d *dockerExecutor) copyToContainer(containerID, path string, content io.Reader, size int64, mode os.FileMode) error {
+	archive := transfer.TarFile(pathpkg.Base(path), size, mode, content)
+	defer archive.Close()
+	return d.client.CopyToContainer(d.ctx, containerID, pathpkg.Dir(path), archive, container.CopyToContainerOptions{})
+}
+
+// copyFromContainer writes the file at path in the container to content. size is called with its size first.
+func (d *dockerExecutor) copyFromContainer(containerID, path string, content io.Writer, size func(int64)) error {
+	archive, stat, err := d.client.CopyFromContainer(d.ctx, containerID, path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if !stat.Mode.IsRegular() {
+		return transfer.ErrNotReadable
+	}
+	size(stat.Size)
+	return transfer.UntarFile(archive, content)
+}
+
+// setupTerminal connects the terminal to the exec session, recording it when recorder is not nil.
+func setupTerminal(execConn types.HijackedResponse, recorder *recording.Recorder) error {
 	fd := int(os.Stdin.Fd())
 	if term.IsTerminal(fd) {
 		state, err := term.MakeRaw(fd)
@@ -106,8 +183,20 @@ func setupTerminal(execConn types.HijackedResponse) error {
 		defer term.Restore(fd, state)
 	}
 
-	go io.Copy(execConn.Conn, os.Stdin)
-	stdcopy.StdCopy(os.Stdout, os.Stderr, execConn.Reader)
+	var stdout io.Writer = os.Stdout
+	if recorder != nil {
+		stdout = io.MultiWriter(os.Stdout, recorder.Output())
+	}
+
+	stopStdin, err := recording.CopyStdin(execConn.Conn, recorder)
+	if err != nil {
+		return err
+	}
+
+	stdcopy.StdCopy(stdout, os.Stderr, execConn.Reader)
+	// Closing the connection first makes sure the copy of stdin is not blocked writing to it.
+	execConn.Close()
+	stopStdin()
 	return nil
 }
 
@@ -118,8 +207,8 @@ var localCmd = &cobra.Command{
 This command provides an interactive prompt to select a running or stopped container from your local Docker environment and choose a shell (such as /bin/sh or /bin/bash) to execute within the container.
 The tool then establishes an interactive terminal session, allowing you to run commands directly inside the selected container.
 `,
-	RunE: func(_ *cobra.Command, _ []string) error {
-		ctx := context.Background()
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
 
 		executor, err := newDockerExecutor(ctx)
 		if err != nil {
@@ -132,7 +221,7 @@ The tool then establishes an interactive terminal session, allowing you to run c
 		}
 
 		if len(containerNames) == 0 {
-			return fmt.Errorf("no running containers found")
+			return apperr.NotFound("no running containers found")
 		}
 
 		selectedContainer, err := selectOption("Select Container", containerNames)
@@ -140,12 +229,32 @@ The tool then establishes an interactive terminal session, allowing you to run c
 			return err
 		}
 
-		selectedShell, err := selectOption("Select Shell", []string{defaultShellSh, defaultShellBash})
+		selectedShell := userSettings.Shell
+		if selectedShell == "" {
+			selectedShell, err = selectOption("Select Shell", []string{defaultShellSh, defaultShellBash})
+			if err != nil {
+				return err
+			}
+		}
+
+		recorder, err := startRecording(selectedContainer)
 		if err != nil {
 			return err
 		}
+		if recorder != nil {
+			defer recorder.Close()
+		}
 
-		return executor.executeInContainer(containerMap[selectedContainer], selectedShell)
+		endAudit := auditSession(ctx, nil, audit.Event{Type: settings.TargetTypeLocal, Target: selectedContainer})
+		err = executor.executeInContainer(containerMap[selectedContainer], selectedShell, recorder)
+		endAudit(err)
+
+		// The exit code of the shell is only audited, it is not an error of eclogin.
+		var exitStatus *audit.ExitStatusError
+		if errors.As(err, &exitStatus) {
+			return nil
+		}
+		return err
 	},
 }
 
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/aws/autoscaling"
+	"eclogin/pkg/aws/ec2"
+	"eclogin/pkg/aws/elbv2"
+	"eclogin/pkg/prompt"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_autoscaling "github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	aws_ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	aws_elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/spf13/cobra"
+)
+
+const (
+	poolStrategyPick        = "pick"
+	poolStrategyRandom      = "random"
+	poolStrategyNewest      = "newest"
+	poolStrategyLeastRecent = "least-recent"
+
+	poolColumnFormat = "%-18s %-20s %s"
+)
+
+var poolStrategies = []string{poolStrategyPick, poolStrategyRandom, poolStrategyNewest, poolStrategyLeastRecent}
+
+// poolMember is an instance of an Auto Scaling group or target group, with its state in the group.
+type poolMember struct {
+	ec2.Instance
+	GroupState string
+}
+
+// selectPoolInstance returns an instance of the group given by --asg or --target-group,
+// chosen with the strategy given by --strategy.
+func selectPoolInstance(cmd *cobra.Command, cfg aws.Config, prompter prompt.Prompter) (string, error) {
+	strategy, _ := cmd.Flags().GetString("strategy")
+	if !slices.Contains(poolStrategies, strategy) {
+		return "", apperr.InvalidInput("unknown strategy %q, expected one of %s", strategy, strings.Join(poolStrategies, ", "))
+	}
+
+	members, err := listPoolMembers(cmd, cfg)
+	if err != nil {
+		return "", err
+	}
+	if len(members) == 0 {
+		return "", apperr.NotFound("no healthy instances in service found")
+	}
+
+	var lastConnected map[string]time.Time
+	switch strategy {
+	case poolStrategyPick:
+		return selectPoolMember(members, prompter)
+	case poolStrategyLeastRecent:
+		if lastConnected, err = lastConnections(); err != nil {
+			return "", apperr.Wrap(err, "failed to load history")
+		}
+	}
+
+	member := pickPoolMember(members, strategy, lastConnected)
+	fmt.Printf("Selected %s (%s) with the %s strategy\n\n", member.DisplayName(), member.GroupState, strategy)
+	return member.ID, nil
+}
+
+// listPoolMembers returns the healthy instances of the Auto Scaling group or target group, sorted by ID.
+func listPoolMembers(cmd *cobra.Command, cfg aws.Config) ([]poolMember, error) {
+	ctx := cmd.Context()
+	states := make(map[string]string)
+	if asg, _ := cmd.Flags().GetString("asg"); asg != "" {
+		instances, err := autoscaling.ListInServiceInstances(ctx, aws_autoscaling.NewFromConfig(cfg), asg)
+		if err != nil {
+			return nil, apperr.Wrap(err, "failed to list instances of Auto Scaling group")
+		}
+		for _, instance := range instances {
+			states[instance.ID] = instance.State()
+		}
+	} else {
+		targetGroup, _ := cmd.Flags().GetString("target-group")
+		targets, err := elbv2.ListHealthyTargets(ctx, aws_elbv2.NewFromConfig(cfg), targetGroup)
+		if err != nil {
+			return nil, apperr.Wrap(err, "failed to list targets of target group")
+		}
+		for _, target := range targets {
+			states[target.ID] = target.State
+		}
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+
+	instanceIDs := make([]string, 0, len(states))
+	for id := range states {
+		instanceIDs = append(instanceIDs, id)
+	}
+	instances, err := ec2.ListInstancesByID(ctx, aws_ec2.NewFromConfig(cfg), instanceIDs)
+	if err != nil {
+		return nil, apperr.Wrap(err, "failed to list EC2 instances")
+	}
+
+	members := make([]poolMember, len(instances))
+	for i, instance := range instances {
+		members[i] = poolMember{Instance: instance, GroupState: states[instance.ID]}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members, nil
+}
+
+func selectPoolMember(members []poolMember, prompter prompt.Prompter) (string, error) {
+	displayNames := make([]string, len(members))
+	for i, member := range members {
+		displayNames[i] = fmt.Sprintf(poolColumnFormat, member.GroupState, member.LaunchTime.Local().Format(historyTimeFormat), member.DisplayName())
+	}
+
+	selected, err := prompter.Select("Select EC2 Instance", displayNames)
+	if err != nil {
+		return "", err
+	}
+	for i, displayName := range displayNames {
+		if displayName == selected {
+			return members[i].ID, nil
+		}
+	}
+	return "", apperr.NotFound("selected instance not found: %s", selected)
+}
+
+// pickPoolMember chooses one of members without prompting. For the least-recent strategy, lastConnected maps
+// instance IDs to the time of their latest connection, and instances never connected to come first.
+func pickPoolMember(members []poolMember, strategy string, lastConnected map[string]time.Time) poolMember {
+	picked := members[0]
+	switch strategy {
+	case poolStrategyRandom:
+		picked = members[rand.IntN(len(members))]
+	case poolStrategyNewest:
+		for _, member := range members[1:] {
+			if member.LaunchTime.After(picked.LaunchTime) {
+				picked = member
+			}
+		}
+	case poolStrategyLeastRecent:
+		for _, member := range members[1:] {
+			if lastConnected[member.ID].Before(lastConnected[picked.ID]) {
+				picked = member
+			}
+		}
+	}
+	return picked
+}
+
+// lastConnections maps the instance IDs in the history to the time of their latest connection.
+func lastConnections() (map[string]time.Time, error) {
+	store, err := historyStore()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	lastConnected := make(map[string]time.Time)
+	for _, entry := range entries {
+		if entry.InstanceID != "" && entry.Timestamp.After(lastConnected[entry.InstanceID]) {
+			lastConnected[entry.InstanceID] = entry.Timestamp
+		}
+	}
+	return lastConnected, nil
+}
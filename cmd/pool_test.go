@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"eclogin/pkg/aws/ec2"
+	"testing"
+	"time"
+)
+
+func TestPickPoolMember(t *testing.T) {
+	now := time.Now()
+	members := []poolMember{
+		{Instance: ec2.Instance{ID: "i-1", LaunchTime: now.Add(-3 * time.Hour)}},
+		{Instance: ec2.Instance{ID: "i-2", LaunchTime: now.Add(-1 * time.Hour)}},
+		{Instance: ec2.Instance{ID: "i-3", LaunchTime: now.Add(-2 * time.Hour)}},
+	}
+
+	tests := []struct {
+		name          string
+		strategy      string
+		lastConnected map[string]time.Time
+		expected      string
+	}{
+		{name: "newest", strategy: poolStrategyNewest, expected: "i-2"},
+		{
+			name:          "least recent",
+			strategy:      poolStrategyLeastRecent,
+			lastConnected: map[string]time.Time{"i-1": now.Add(-time.Minute), "i-2": now.Add(-time.Hour), "i-3": now},
+			expected:      "i-2",
+		},
+		{
+			name:          "never connected first",
+			strategy:      poolStrategyLeastRecent,
+			lastConnected: map[string]time.Time{"i-1": now.Add(-time.Minute), "i-2": now.Add(-time.Hour)},
+			expected:      "i-3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if picked := pickPoolMember(members, tt.strategy, tt.lastConnected); picked.ID != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, picked.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/history"
+	"eclogin/pkg/prompt"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const (
+	defaultReconnectRetries    = 5
+	defaultReconnectBackoff    = 2 * time.Second
+	defaultReconnectMaxBackoff = 30 * time.Second
+
+	// stableSessionDuration is how long a session must last before its abnormal end counts as a drop.
+	// A plugin failing right after it starts, e.g. for a bad target, is not reconnected, and a reconnected
+	// session failing that early counts as a failed attempt, so that it is retried with backoff rather than in a loop.
+	stableSessionDuration = 30 * time.Second
+)
+
+// reconnectPolicy returns the reconnect settings with defaults for the values that are not set.
+func reconnectPolicy() (retries int, backoff, maxBackoff time.Duration) {
+	config := userSettings.Reconnect
+	retries, backoff, maxBackoff = defaultReconnectRetries, defaultReconnectBackoff, defaultReconnectMaxBackoff
+	if config.Retries > 0 {
+		retries = config.Retries
+	}
+	if config.Backoff > 0 {
+		backoff = config.Backoff
+	}
+	if config.MaxBackoff > 0 {
+		maxBackoff = config.MaxBackoff
+	}
+	return retries, backoff, maxBackoff
+}
+
+// sessionDropped reports whether err is an abnormal exit of the session manager plugin after the session
+// lasted long enough to have worked, e.g. after a network drop, as opposed to a failure to start the session,
+// a plugin failing right away or a cancellation by a signal.
+func sessionDropped(ctx context.Context, err error, lasted time.Duration) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && ctx.Err() == nil && lasted >= stableSessionDuration
+}
+
+// reconnectDropped offers to reconnect to the target of entry while its session, started at started,
+// ends abnormally with err, and returns the error of the last session. It returns err as is when the
+// session ended normally.
+func reconnectDropped(ctx context.Context, cfg aws.Config, entry history.Entry, started time.Time, err error) error {
+	if userSettings.Reconnect.Disabled {
+		return err
+	}
+
+	for sessionDropped(ctx, err, time.Since(started)) {
+		fmt.Fprintf(os.Stderr, "\nThe session ended unexpectedly: %v\n", err)
+		if !userSettings.Reconnect.Auto {
+			confirmed, confirmErr := confirmReconnect()
+			if confirmErr != nil || !confirmed {
+				return err
+			}
+		}
+		started, err = retryReopen(ctx, cfg, entry)
+	}
+	return err
+}
+
+func confirmReconnect() (bool, error) {
+	if !prompt.Interactive() {
+		return false, nil
+	}
+	return confirm("Reconnect to the same target?")
+}
+
+// retryReopen reopens the session, retrying with exponential backoff while it fails to start, and returns
+// when the last attempt started. Errors that another attempt cannot fix, such as missing permissions, are returned at once.
+func retryReopen(ctx context.Context, cfg aws.Config, entry history.Entry) (time.Time, error) {
+	retries, backoff, maxBackoff := reconnectPolicy()
+
+	var start time.Time
+	var err error
+	for attempt := 1; attempt <= retries; attempt++ {
+		if attempt > 1 {
+			fmt.Fprintf(os.Stderr, "Retrying in %v\n", backoff)
+			select {
+			case <-ctx.Done():
+				return start, apperr.ErrCancelled
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+
+		fmt.Fprintf(os.Stderr, "Reconnecting (attempt %d/%d)\n", attempt, retries)
+		start = time.Now()
+		err = reopen(ctx, cfg, entry)
+		if err == nil || ctx.Err() != nil || apperr.KindOf(err) != apperr.KindGeneral {
+			return start, err
+		}
+		if sessionDropped(ctx, err, time.Since(start)) {
+			return start, err
+		}
+		fmt.Fprintf(os.Stderr, "Failed to reconnect: %v\n", err)
+	}
+	return start, err
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/history"
+	"eclogin/pkg/settings"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestSessionDropped(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	if exitErr == nil {
+		t.Fatal("expected the command to fail")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		lasted   time.Duration
+		expected bool
+	}{
+		{name: "normal exit", ctx: context.Background(), err: nil, lasted: time.Hour, expected: false},
+		{name: "plugin exit after a stable session", ctx: context.Background(), err: apperr.Wrap(exitErr, "failed to execute instance session"), lasted: time.Hour, expected: true},
+		{name: "plugin exit right after start", ctx: context.Background(), err: apperr.Wrap(exitErr, "failed to execute instance session"), lasted: time.Second, expected: false},
+		{name: "start failure", ctx: context.Background(), err: errors.New("start session failed"), lasted: time.Hour, expected: false},
+		{name: "signal", ctx: cancelled, err: exitErr, lasted: time.Hour, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if dropped := sessionDropped(tt.ctx, tt.err, tt.lasted); dropped != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, dropped)
+			}
+		})
+	}
+}
+
+func TestReconnectPolicy(t *testing.T) {
+	original := userSettings
+	defer func() { userSettings = original }()
+
+	userSettings = &settings.Settings{}
+	retries, backoff, maxBackoff := reconnectPolicy()
+	if retries != defaultReconnectRetries || backoff != defaultReconnectBackoff || maxBackoff != defaultReconnectMaxBackoff {
+		t.Errorf("expected defaults, got %d %v %v", retries, backoff, maxBackoff)
+	}
+
+	userSettings = &settings.Settings{Reconnect: settings.Reconnect{Retries: 10, Backoff: time.Second}}
+	retries, backoff, maxBackoff = reconnectPolicy()
+	if retries != 10 || backoff != time.Second || maxBackoff != defaultReconnectMaxBackoff {
+		t.Errorf("expected the settings to override the defaults, got %d %v %v", retries, backoff, maxBackoff)
+	}
+}
+
+func TestReconnectDroppedDisabled(t *testing.T) {
+	original := userSettings
+	defer func() { userSettings = original }()
+	userSettings = &settings.Settings{Reconnect: settings.Reconnect{Disabled: true}}
+
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	if err := reconnectDropped(context.Background(), aws.Config{}, history.Entry{}, time.Now().Add(-time.Hour), exitErr); err != exitErr {
+		t.Errorf("expected the session error to be returned, got %v", err)
+	}
+}
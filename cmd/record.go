@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"eclogin/pkg/recording"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const recordTimeFormat = "20060102-150405"
+
+// recordFile is the --record flag of the commands that start sessions.
+var recordFile string
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <file>",
+	Short: "Play back a session recorded with --record",
+	Long: `The replay command plays back an asciicast recording in the terminal with its original timing.
+Recordings can also be played with asciinema or uploaded to an asciinema server.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplayCommand,
+}
+
+func runReplayCommand(cmd *cobra.Command, args []string) error {
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	speed, _ := cmd.Flags().GetFloat64("speed")
+	maxIdle, _ := cmd.Flags().GetDuration("max-idle")
+	_, err = recording.Replay(cmd.Context(), file, os.Stdout, speed, maxIdle)
+	return err
+}
+
+// startRecording creates the recorder of a session with target, at the --record path or in the
+// record_dir of the settings file. It returns nil when the session is not recorded.
+func startRecording(target string) (*recording.Recorder, error) {
+	path := recordFile
+	if path == "" && userSettings.RecordDir != "" {
+		dir, err := expandHome(userSettings.RecordDir)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.NewReplacer("/", "_", ":", "_", " ", "_").Replace(target)
+		path = filepath.Join(dir, fmt.Sprintf("%s-%s.cast", time.Now().Format(recordTimeFormat), name))
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	width, height, _ := term.GetSize(int(os.Stdout.Fd()))
+	recorder, err := recording.Create(path, width, height, target)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Recording session to %s\n", path)
+	return recorder, nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+}
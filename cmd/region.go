@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/aws/config"
+	"eclogin/pkg/aws/ec2"
+	"eclogin/pkg/prompt"
+	"errors"
+	"fmt"
+	"sync"
+
+	aws_ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/spf13/cobra"
+)
+
+const regionColumnFormat = "%-16s %s"
+
+// regionalItem is a resource found by searchAllRegions, tagged with the region it lives in.
+type regionalItem[T any] struct {
+	Region string
+	Item   T
+}
+
+// getRegion returns the --region flag or the default region from the settings file, or lets the user pick
+// one of the regions enabled for the account. It falls back to free text input when the regions cannot be listed.
+func getRegion(cmd *cobra.Command, profile string, prompter prompt.Prompter) (string, error) {
+	if region, _ := cmd.Flags().GetString("region"); region != "" {
+		return region, nil
+	}
+	if userSettings.DefaultRegion != "" {
+		return userSettings.DefaultRegion, nil
+	}
+
+	if !prompt.Interactive() {
+		return preferredRegion(), nil
+	}
+
+	regions, err := listRegions(cmd.Context(), profile)
+	if err != nil || len(regions) == 0 {
+		return prompt.GetFlagOrInput(cmd, "region", "Please enter AWS region", preferredRegion(), prompter)
+	}
+	return prompt.GetFlagOrSelect(cmd, "region", "Select AWS Region", regions, prompter)
+}
+
+// listRegions lists the enabled regions with the preferred region first, so it is preselected in the picker.
+func listRegions(ctx context.Context, profile string) ([]string, error) {
+	cfg, err := config.LoadConfig(ctx, preferredRegion(), profile)
+	if err != nil {
+		return nil, err
+	}
+
+	regions, err := ec2.ListRegions(ctx, aws_ec2.NewFromConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := []string{}
+	for _, region := range regions {
+		if region == cfg.Region {
+			ordered = append([]string{region}, ordered...)
+		} else {
+			ordered = append(ordered, region)
+		}
+	}
+	return ordered, nil
+}
+
+// searchAllRegions calls search concurrently for every enabled region and collects the results
+// in region order. Regions that fail are skipped; their errors are only returned when nothing was found.
+func searchAllRegions[T any](ctx context.Context, profile string, search func(region string) ([]T, error)) ([]regionalItem[T], error) {
+	regions, err := listRegions(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := searchConcurrently(regions, search)
+
+	var items []regionalItem[T]
+	for i, region := range regions {
+		for _, item := range results[i] {
+			items = append(items, regionalItem[T]{Region: region, Item: item})
+		}
+	}
+
+	if len(items) == 0 && err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// searchConcurrently calls search for every key at once and returns the results in key order,
+// together with the errors of the keys that failed.
+func searchConcurrently[T any](keys []string, search func(key string) ([]T, error)) ([][]T, error) {
+	results := make([][]T, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			items, err := search(key)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", key, err)
+				return
+			}
+			results[i] = items
+		}(i, key)
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
@@ -1,37 +1,106 @@
 package cmd
 
 import (
+	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/aws/config"
+	"eclogin/pkg/interrupt"
+	"eclogin/pkg/prompt"
+	"eclogin/pkg/settings"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 const (
 	appVersion = "0.0.20"
 	appName    = "eclogin"
+
+	defaultRegion = "ap-northeast-1"
+
+	defaultTimeout = 30 * time.Second
 )
 
 var rootCmd = &cobra.Command{
-	Use:     appName,
-	Version: appVersion,
-	Short:   "CLI tool for logging into AWS EC2/ECS/Local docker containers",
-	Long:    `A command-line interface tool that helps you connect to AWS EC2 instances and ECS containers.`,
+	Use:           appName,
+	Version:       appVersion,
+	Short:         "CLI tool for logging into AWS EC2/ECS/Local docker containers",
+	Long:          `A command-line interface tool that helps you connect to AWS EC2 instances and ECS containers.`,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		// Usage is only useful for flag and argument errors, which are reported before this runs.
+		cmd.SilenceUsage = true
+
+		applyAWSFlags(cmd)
+
+		noInput, _ := cmd.Flags().GetBool("no-input")
+		prompt.SetInteractive(!noInput && term.IsTerminal(int(os.Stdin.Fd())))
+
+		loaded, err := settings.Load()
+		if err != nil {
+			return apperr.Wrap(err, "failed to load settings")
+		}
+		userSettings = loaded
+		return nil
+	},
 }
 
+// applyAWSFlags sets the endpoint and request timeout of the AWS clients from --endpoint-url and --timeout.
+func applyAWSFlags(cmd *cobra.Command) {
+	endpointURL, _ := cmd.Flags().GetString("endpoint-url")
+	config.SetEndpointURL(endpointURL)
+
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	config.SetTimeout(timeout)
+}
+
+// Execute runs the root command and exits with the code of the error kind, printing a hint
+// for common AWS errors. SIGINT, SIGTERM and SIGHUP cancel the AWS calls in flight, and SIGTERM and
+// SIGHUP end a running session so that it is terminated. SIGINT during a session goes to the session.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := interrupt.NotifyContext(context.Background())
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
 	if err != nil {
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if hint := apperr.Hint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+		}
+		os.Exit(apperr.ExitCode(err))
 	}
 }
 
 func init() {
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &apperr.Error{Kind: apperr.KindInvalidInput, Err: err}
+	})
 	rootCmd.Flags().BoolP("toggle", "t", false, "Toggle feature flag")
+	rootCmd.PersistentFlags().String("endpoint-url", "", "Override the endpoint URL of all AWS services")
+	rootCmd.PersistentFlags().Bool("no-input", false, "Never prompt; fail when a required value is missing")
+	rootCmd.PersistentFlags().Bool("refresh", false, "Ignore cached resource listings and fetch them again")
+	rootCmd.PersistentFlags().Duration("timeout", defaultTimeout, "Timeout of each AWS request (0 disables it)")
 
 	// EC2 command flags
 	ec2Cmd.Flags().StringP("region", "r", "", "AWS region name")
 	ec2Cmd.Flags().StringP("profile", "p", "", "AWS profile name")
 	ec2Cmd.Flags().StringP("instance-id", "i", "", "EC2 instance ID")
+	ec2Cmd.Flags().Bool("all-regions", false, "Search instances in all enabled regions")
+	ec2Cmd.Flags().Bool("all-accounts", false, "Search instances in all accounts of the organization")
+	ec2Cmd.Flags().StringSlice("accounts", nil, "Account IDs to search instead of the organization accounts")
+	ec2Cmd.Flags().String("role-name", defaultRoleName, "Role name to assume in each account")
+	ec2Cmd.Flags().Bool("start", false, "Start the instance without asking when it is stopped")
+	ec2Cmd.Flags().Bool("stop-on-exit", false, "Stop the instance when the session ends")
+	ec2Cmd.Flags().String("command", "", "Run this command instead of a shell (AWS-StartInteractiveCommand)")
+	ec2Cmd.Flags().String("document-name", "", "Session document to start the session with")
+	ec2Cmd.Flags().StringArray("parameters", nil, "Parameter of the session document as key=value (repeatable)")
+	ec2Cmd.Flags().Bool("select-document", false, "Select the session document from the Session documents of the account")
+	ec2Cmd.Flags().String("asg", "", "Connect to an instance of this Auto Scaling group")
+	ec2Cmd.Flags().String("target-group", "", "Connect to an instance of this target group (name or ARN)")
+	ec2Cmd.Flags().String("strategy", poolStrategyPick, "How to choose an instance of --asg or --target-group: "+strings.Join(poolStrategies, ", "))
 
 	// ECS command flags
 	ecsCmd.Flags().StringP("region", "r", "", "AWS region name")
@@ -41,4 +110,55 @@ func init() {
 	ecsCmd.Flags().StringP("task-id", "t", "", "ECS task ID")
 	ecsCmd.Flags().StringP("container", "C", "", "ECS container name")
 	ecsCmd.Flags().StringP("shell", "S", "", "Shell to use for the session")
+	ecsCmd.Flags().Bool("all-regions", false, "Search clusters in all enabled regions")
+	ecsCmd.Flags().Bool("all-accounts", false, "Search services in all accounts of the organization")
+	ecsCmd.Flags().StringSlice("accounts", nil, "Account IDs to search instead of the organization accounts")
+	ecsCmd.Flags().String("role-name", defaultRoleName, "Role name to assume in each account")
+
+	// SSH command flags
+	for _, cmd := range []*cobra.Command{ec2ProxyCmd, sshConfigCmd} {
+		cmd.Flags().StringP("region", "r", "", "AWS region name")
+		cmd.Flags().StringP("profile", "p", "", "AWS profile name")
+	}
+	ec2SSHCmd.Flags().StringP("region", "r", "", "AWS region name")
+	ec2SSHCmd.Flags().StringP("profile", "p", "", "AWS profile name")
+	ec2SSHCmd.Flags().StringP("instance-id", "i", "", "EC2 instance ID")
+	ec2SSHCmd.Flags().StringP("os-user", "u", "", "OS user to log in as (default \""+defaultOSUser+"\")")
+	sshConfigCmd.Flags().String("user", "", "User to log in as")
+	sshConfigCmd.Flags().String("identity-file", "", "Private key to authenticate with")
+	sshConfigCmd.Flags().String("prefix", "", "Prefix of the Host aliases")
+	sshConfigCmd.Flags().String("file", "", "File to write instead of ~/.config/eclogin/ssh_config")
+
+	// Copy command flags
+	cpCmd.Flags().StringP("region", "r", "", "AWS region name")
+	cpCmd.Flags().StringP("profile", "p", "", "AWS profile name")
+
+	// History command flags
+	historyCmd.Flags().BoolP("select", "s", false, "Select a connection to reopen")
+
+	// Session recording flags
+	for _, cmd := range []*cobra.Command{ec2Cmd, ecsCmd, localCmd, connectCmd, historyCmd, lastCmd} {
+		cmd.Flags().StringVar(&recordFile, "record", "", "Record the session to an asciicast file")
+	}
+	replayCmd.Flags().Float64("speed", 1, "Playback speed")
+	replayCmd.Flags().Duration("max-idle", 2*time.Second, "Shorten pauses to this duration (0 keeps them)")
+
+	// List command flags
+	for _, cmd := range []*cobra.Command{sessionsCmd, sessionsTerminateCmd, ec2ListCmd, ecsClustersCmd, ecsServicesCmd, ecsTasksCmd, ecsContainersCmd} {
+		cmd.Flags().StringP("region", "r", "", "AWS region name")
+		cmd.Flags().StringP("profile", "p", "", "AWS profile name")
+		addOutputFlag(cmd)
+	}
+	addOutputFlag(localListCmd)
+	sessionsTerminateCmd.Flags().Bool("all", false, "Terminate all your active sessions")
+	ecsServicesCmd.Flags().StringP("cluster", "c", "", "ECS cluster name")
+	ecsTasksCmd.Flags().StringP("cluster", "c", "", "ECS cluster name")
+	ecsTasksCmd.Flags().StringP("service", "s", "", "ECS service name")
+	ecsContainersCmd.Flags().StringP("cluster", "c", "", "ECS cluster name")
+	ecsContainersCmd.Flags().StringP("task-id", "t", "", "ECS task ID")
+
+	// Dynamic flag completion
+	for _, cmd := range []*cobra.Command{ec2Cmd, ecsCmd, ec2ProxyCmd, ec2SSHCmd, sshConfigCmd, cpCmd, sessionsCmd, sessionsTerminateCmd, ec2ListCmd, ecsClustersCmd, ecsServicesCmd, ecsTasksCmd, ecsContainersCmd, localListCmd} {
+		registerFlagCompletions(cmd)
+	}
 }
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/aws/session"
+	"eclogin/pkg/aws/sts"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	aws_sts "github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+const terminateTimeout = 10 * time.Second
+
+type sessionRecord struct {
+	SessionID string `json:"session_id" yaml:"session_id"`
+	Target    string `json:"target" yaml:"target"`
+	StartDate string `json:"start_date" yaml:"start_date"`
+	Region    string `json:"region" yaml:"region"`
+}
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List your active Session Manager sessions",
+	Long: `The sessions command lists the active Session Manager sessions started by the current
+IAM identity in the region.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, sessions, err := listOwnSessions(cmd)
+		if err != nil {
+			return err
+		}
+
+		records := make([]sessionRecord, len(sessions))
+		for i, s := range sessions {
+			records[i] = sessionRecord{
+				SessionID: s.ID,
+				Target:    s.Target,
+				StartDate: s.StartDate.Local().Format(historyTimeFormat),
+				Region:    cfg.Region,
+			}
+		}
+		return writeRecords(cmd, records)
+	},
+}
+
+var sessionsTerminateCmd = &cobra.Command{
+	Use:   "terminate [session-id...]",
+	Short: "Terminate your active Session Manager sessions",
+	Long: `The terminate command terminates the given sessions. Without arguments, a session
+started by the current IAM identity is selected, or all of them with --all.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		if all && len(args) > 0 {
+			return apperr.InvalidInput("--all cannot be used with session IDs")
+		}
+
+		var cfg aws.Config
+		var err error
+		sessionIDs := args
+		if len(sessionIDs) > 0 {
+			if cfg, err = loadListConfig(cmd); err != nil {
+				return err
+			}
+		} else {
+			var sessions []session.Session
+			if cfg, sessions, err = listOwnSessions(cmd); err != nil {
+				return err
+			}
+			if len(sessions) == 0 {
+				return apperr.NotFound("no active sessions found")
+			}
+			if sessionIDs, err = selectSessions(sessions, all); err != nil {
+				return err
+			}
+		}
+
+		client := ssm.NewFromConfig(cfg)
+		for _, sessionID := range sessionIDs {
+			if err := session.TerminateSession(cmd.Context(), client, sessionID); err != nil {
+				return apperr.Wrap(err, "failed to terminate session")
+			}
+			fmt.Printf("Terminated %s\n", sessionID)
+		}
+		return nil
+	},
+}
+
+// listOwnSessions returns the config of --region and --profile and the active sessions started by its caller identity.
+func listOwnSessions(cmd *cobra.Command) (aws.Config, []session.Session, error) {
+	cfg, err := loadListConfig(cmd)
+	if err != nil {
+		return aws.Config{}, nil, err
+	}
+
+	identity, err := sts.GetCallerIdentity(cmd.Context(), aws_sts.NewFromConfig(cfg))
+	if err != nil {
+		return aws.Config{}, nil, apperr.Wrap(err, "failed to get caller identity")
+	}
+
+	sessions, err := session.ListActiveSessions(cmd.Context(), ssm.NewFromConfig(cfg), identity.ARN)
+	if err != nil {
+		return aws.Config{}, nil, apperr.Wrap(err, "failed to list sessions")
+	}
+	return cfg, sessions, nil
+}
+
+func selectSessions(sessions []session.Session, all bool) ([]string, error) {
+	if all {
+		sessionIDs := make([]string, len(sessions))
+		for i, s := range sessions {
+			sessionIDs[i] = s.ID
+		}
+		return sessionIDs, nil
+	}
+
+	displayNames := make([]string, len(sessions))
+	for i, s := range sessions {
+		displayNames[i] = fmt.Sprintf("%s  %s  %s", s.StartDate.Local().Format(historyTimeFormat), s.Target, s.ID)
+	}
+	selected, err := newPrompter().Select("Select Session", displayNames)
+	if err != nil {
+		return nil, err
+	}
+	for i, displayName := range displayNames {
+		if displayName == selected {
+			return []string{sessions[i].ID}, nil
+		}
+	}
+	return nil, apperr.NotFound("session not found: %s", selected)
+}
+
+// terminateSession terminates a session once the plugin has exited, so that it does not linger
+// until the idle timeout when the plugin crashed or was killed. It runs even when ctx has been
+// cancelled by a signal, and failures are only reported.
+func terminateSession(ctx context.Context, cfg aws.Config, sessionID string) {
+	if sessionID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), terminateTimeout)
+	defer cancel()
+	if err := session.TerminateSession(ctx, ssm.NewFromConfig(cfg), sessionID); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to terminate session: %v\n", err)
+	}
+}
+
+func init() {
+	sessionsCmd.AddCommand(sessionsTerminateCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"eclogin/pkg/prompt"
+	"eclogin/pkg/settings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	answerYes = "Yes"
+	answerNo  = "No"
+)
+
+// userSettings is loaded from the settings file before every command runs.
+var userSettings = &settings.Settings{}
+
+// preferredRegion returns the default region from the settings file, or defaultRegion.
+func preferredRegion() string {
+	if userSettings.DefaultRegion != "" {
+		return userSettings.DefaultRegion
+	}
+	return defaultRegion
+}
+
+// getProfile returns the --profile flag, or the default profile from the settings file without prompting.
+// When neither is set, it prompts for one unless every required flag is given.
+func getProfile(cmd *cobra.Command, requiredFlags []string, prompter prompt.Prompter) (string, error) {
+	if profile := cmd.Flag("profile").Value.String(); profile != "" {
+		return profile, nil
+	}
+	if userSettings.DefaultProfile != "" || prompt.HasRequiredFlags(cmd, requiredFlags) {
+		return userSettings.DefaultProfile, nil
+	}
+	return prompt.GetFlagOrInput(cmd, "profile", "Please enter AWS profile (optional)", userSettings.DefaultProfile, prompter)
+}
+
+// getShell returns the --shell flag, the preferred shell from the settings file, or lets the user pick one.
+func getShell(cmd *cobra.Command, prompter prompt.Prompter) (string, error) {
+	if shell, _ := cmd.Flags().GetString("shell"); shell == "" && userSettings.Shell != "" {
+		return userSettings.Shell, nil
+	}
+	return prompt.GetFlagOrSelect(cmd, "shell", "Select Shell", availableShells, prompter)
+}
+
+// confirm asks a yes or no question. It must only be called when prompting is allowed.
+func confirm(label string) (bool, error) {
+	selected, err := prompt.NewUIPrompter().Select(label, []string{answerYes, answerNo})
+	if err != nil {
+		return false, err
+	}
+	return selected == answerYes, nil
+}
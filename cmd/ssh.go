@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"eclogin/pkg/apperr"
+	"eclogin/pkg/aws/config"
+	"eclogin/pkg/aws/ec2"
+	"eclogin/pkg/aws/instanceconnect"
+	"eclogin/pkg/interrupt"
+	"eclogin/pkg/prompt"
+	"eclogin/pkg/settings"
+	"eclogin/pkg/sshconfig"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	aws_ec2instanceconnect "github.com/aws/aws-sdk-go-v2/service/ec2instanceconnect"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	sshDocumentName = "AWS-StartSSHSession"
+	defaultSSHPort  = "22"
+	defaultOSUser   = "ec2-user"
+)
+
+var ec2ProxyCmd = &cobra.Command{
+	Use:   "proxy <instance> [port]",
+	Short: "Connect stdin and stdout to SSH on an instance, for use as an SSH ProxyCommand",
+	Long: `The proxy command starts an AWS-StartSSHSession session with an instance and connects it
+to stdin and stdout, so that ssh, scp and rsync can reach private instances through Session Manager.
+The instance is an instance ID or the Name tag of a running instance.
+
+  Host i-* mi-*
+    ProxyCommand eclogin ec2 proxy %h %p --region ap-northeast-1`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runEC2ProxyCommand,
+}
+
+var ec2SSHCmd = &cobra.Command{
+	Use:   "ssh [-- ssh-args...]",
+	Short: "Open SSH to an instance through Session Manager with an ephemeral key",
+	Long: `The ssh command generates an ephemeral key pair, pushes its public key for the OS user with
+EC2 Instance Connect and runs ssh with it through eclogin ec2 proxy. The key is accepted for 60 seconds
+and deleted when ssh exits, so no long-lived key has to be installed on the instance.
+Arguments after -- are passed to ssh, e.g. -A for agent forwarding or -L for port forwarding.`,
+	RunE: runEC2SSHCommand,
+}
+
+var sshConfigCmd = &cobra.Command{
+	Use:   "ssh-config",
+	Short: "Generate SSH Host blocks for EC2 instances",
+	Long: `The ssh-config command writes a Host block for every instance of the region, named after
+its Name tag, to ~/.config/eclogin/ssh_config. The blocks connect through eclogin ec2 proxy,
+so eclogin must be on the PATH that ssh runs the ProxyCommand with.
+Each region and profile is kept in its own section, which is replaced when the command is run again.
+Include the file at the top of ~/.ssh/config to use it.`,
+	Args: cobra.NoArgs,
+	RunE: runSSHConfigCommand,
+}
+
+func runEC2ProxyCommand(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	port := defaultSSHPort
+	if len(args) == 2 {
+		port = args[1]
+	}
+
+	cfg, err := loadListConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	instanceID := args[0]
+	if !isInstanceID(instanceID) {
+		if instanceID, err = resolveAliasInstance(ctx, cfg, settings.Alias{Name: args[0]}); err != nil {
+			return apperr.Wrap(err, "failed to resolve EC2 instance")
+		}
+	}
+
+	input := &ssm.StartSessionInput{
+		Target:       aws.String(instanceID),
+		DocumentName: aws.String(sshDocumentName),
+		Parameters:   map[string][]string{"portNumber": {port}},
+	}
+	if err := startInstanceSession(ctx, cfg, input, false); err != nil {
+		return apperr.Wrap(err, "failed to start SSH session")
+	}
+	return nil
+}
+
+func runEC2SSHCommand(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	requiredFlags := []string{"instance-id", "region"}
+	prompter := newPrompter()
+
+	profile, err := getProfile(cmd, requiredFlags, prompter)
+	if err != nil {
+		return err
+	}
+	region, err := getRegion(cmd, profile, prompter)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig(ctx, region, profile)
+	if err != nil {
+		return apperr.Wrap(err, "failed to load AWS config")
+	}
+	setScope(ctx, cfg, profile)
+
+	instanceID, err := getInstanceID(cmd, cfg, prompter)
+	if err != nil {
+		return err
+	}
+	osUser, err := prompt.GetFlagOrInput(cmd, "os-user", "OS user", defaultOSUser, prompter)
+	if err != nil {
+		return err
+	}
+
+	keyDir, err := os.MkdirTemp("", appName+"-ssh-")
+	if err != nil {
+		return apperr.Wrap(err, "failed to create a directory for the key")
+	}
+	defer os.RemoveAll(keyDir)
+
+	keyPair, err := instanceconnect.GenerateKeyPair(appName)
+	if err != nil {
+		return apperr.Wrap(err, "failed to generate SSH key")
+	}
+	keyPath := filepath.Join(keyDir, "id_ed25519")
+	if err := os.WriteFile(keyPath, keyPair.PrivateKey, 0600); err != nil {
+		return apperr.Wrap(err, "failed to write SSH key")
+	}
+
+	if err := instanceconnect.SendSSHPublicKey(ctx, aws_ec2instanceconnect.NewFromConfig(cfg), instanceID, osUser, keyPair.PublicKey); err != nil {
+		return apperr.Wrap(err, "failed to push SSH key")
+	}
+
+	proxy := proxyCommand(currentExecutable(), region, profile, forwardedFlags(cmd))
+	sshCmd := exec.Command("ssh", sshArgs(keyPath, proxy, osUser, instanceID, args)...)
+	sshCmd.Stdin = os.Stdin
+	sshCmd.Stdout = os.Stdout
+	sshCmd.Stderr = os.Stderr
+	defer interrupt.PassThrough()()
+
+	// The connection is not recorded in the history, which would reopen it as a shell session instead of SSH.
+	if err := sshCmd.Run(); err != nil {
+		return apperr.Wrap(err, "ssh failed")
+	}
+	return nil
+}
+
+// sshArgs returns the arguments of ssh to log in to the instance as osUser with the key at keyPath.
+// Only that key is offered, since the instance may reject the connection after too many attempts.
+func sshArgs(keyPath, proxy, osUser, instanceID string, extra []string) []string {
+	args := []string{
+		"-i", keyPath,
+		"-o", "IdentitiesOnly=yes",
+		"-o", "ProxyCommand=" + proxy,
+	}
+	args = append(args, extra...)
+	return append(args, osUser+"@"+instanceID)
+}
+
+// proxyCommand returns the SSH ProxyCommand that runs executable ec2 proxy in the region,
+// with the global flags given by forwardedFlags.
+func proxyCommand(executable, region, profile string, flags []string) string {
+	command := fmt.Sprintf("%s ec2 proxy %%h %%p --region %s", executable, region)
+	if profile != "" {
+		command += " --profile " + profile
+	}
+	for _, flag := range flags {
+		command += " " + flag
+	}
+	return command
+}
+
+// forwardedFlags returns the global flags of cmd that the proxy must be run with to reach the same
+// endpoints, such as --endpoint-url and --timeout.
+func forwardedFlags(cmd *cobra.Command) []string {
+	var flags []string
+	if endpointURL, _ := cmd.Flags().GetString("endpoint-url"); endpointURL != "" {
+		flags = append(flags, "--endpoint-url "+shellQuote(endpointURL))
+	}
+	if cmd.Flags().Changed("timeout") {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		flags = append(flags, "--timeout "+timeout.String())
+	}
+	return flags
+}
+
+// currentExecutable returns the path of the running eclogin, so that a ProxyCommand started right away
+// works when it is not in PATH. Paths that would need quoting fall back to the name.
+func currentExecutable() string {
+	executable, err := os.Executable()
+	if err != nil || strings.ContainsAny(executable, " \t\"'") {
+		return appName
+	}
+	return executable
+}
+
+// isInstanceID reports whether target is an EC2 instance ID or a managed instance ID rather than a Name tag.
+func isInstanceID(target string) bool {
+	return strings.HasPrefix(target, "i-") || strings.HasPrefix(target, "mi-")
+}
+
+func runSSHConfigCommand(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadListConfig(cmd)
+	if err != nil {
+		return err
+	}
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile == "" {
+		profile = userSettings.DefaultProfile
+	}
+
+	instances, err := ec2.ListInstances(cmd.Context(), aws_ec2.NewFromConfig(cfg))
+	if err != nil {
+		return apperr.Wrap(err, "failed to list EC2 instances")
+	}
+
+	user, _ := cmd.Flags().GetString("user")
+	identityFile, _ := cmd.Flags().GetString("identity-file")
+	prefix, _ := cmd.Flags().GetString("prefix")
+	hosts := sshHosts(instances, prefix)
+	for i := range hosts {
+		hosts[i].User = user
+		hosts[i].IdentityFile = identityFile
+		// The file outlives this binary, whose path may be temporary (go run) or change on upgrade,
+		// so the proxy is looked up on the PATH of ssh instead of using currentExecutable.
+		hosts[i].ProxyCommand = proxyCommand(appName, cfg.Region, profile, forwardedFlags(cmd))
+	}
+
+	path, err := sshConfigPath(cmd)
+	if err != nil {
+		return err
+	}
+	section := cfg.Region
+	if profile != "" {
+		section = profile + "/" + cfg.Region
+	}
+	if err := sshconfig.WriteSection(path, section, hosts); err != nil {
+		return apperr.Wrap(err, "failed to write ssh config")
+	}
+	fmt.Printf("Wrote %d hosts to %s\n", len(hosts), path)
+
+	if home, err := os.UserHomeDir(); err == nil && !sshconfig.Includes(filepath.Join(home, ".ssh", "config"), path) {
+		fmt.Printf("Add this line at the top of ~/.ssh/config to use them:\n  Include %s\n", path)
+	}
+	return nil
+}
+
+// sshHosts returns a Host block for each instance that is not terminated, named after its Name tag.
+// Instances without a name, or sharing it with another instance, are suffixed with their ID.
+func sshHosts(instances []ec2.Instance, prefix string) []sshconfig.Host {
+	counts := make(map[string]int)
+	for _, instance := range instances {
+		counts[sshconfig.Alias(instance.Name)]++
+	}
+
+	var hosts []sshconfig.Host
+	for _, instance := range instances {
+		if instance.State == "terminated" || instance.State == "shutting-down" {
+			continue
+		}
+
+		alias := sshconfig.Alias(instance.Name)
+		switch {
+		case alias == "":
+			alias = instance.ID
+		case counts[alias] > 1:
+			alias += "-" + instance.ID
+		}
+		hosts = append(hosts, sshconfig.Host{Alias: prefix + alias, HostName: instance.ID})
+	}
+	return hosts
+}
+
+func sshConfigPath(cmd *cobra.Command) (string, error) {
+	if path, _ := cmd.Flags().GetString("file"); path != "" {
+		return expandHome(path)
+	}
+	dir, err := settings.Dir()
+	if err != nil {
+		return "", apperr.Wrap(err, "failed to resolve the settings directory")
+	}
+	return filepath.Join(dir, "ssh_config"), nil
+}
+
+func init() {
+	ec2Cmd.AddCommand(ec2ProxyCmd, ec2SSHCmd)
+	rootCmd.AddCommand(sshConfigCmd)
+}
@@ -0,0 +1,152 @@
+// Package apperr defines the errors reported by eclogin, the exit codes they map to
+// and hints for common AWS errors.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+type Kind int
+
+const (
+	KindGeneral Kind = iota
+	KindInvalidInput
+	KindNotFound
+	KindPermissionDenied
+	KindAuthentication
+	KindCancelled
+)
+
+// Exit codes of the kinds. Cancellation follows the shell convention for SIGINT.
+const (
+	ExitGeneral          = 1
+	ExitInvalidInput     = 2
+	ExitNotFound         = 3
+	ExitPermissionDenied = 4
+	ExitAuthentication   = 5
+	ExitCancelled        = 130
+)
+
+// Error is an error with the kind that decides its exit code.
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	default:
+		return e.Message + ": " + e.Err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrCancelled is returned when the user aborts a prompt.
+var ErrCancelled = &Error{Kind: KindCancelled, Message: "cancelled by user"}
+
+func New(kind Kind, format string, args ...any) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(format string, args ...any) error {
+	return New(KindNotFound, format, args...)
+}
+
+func InvalidInput(format string, args ...any) error {
+	return New(KindInvalidInput, format, args...)
+}
+
+// Wrap adds a message to err and keeps its kind. It returns nil when err is nil.
+func Wrap(err error, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: KindOf(err), Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// KindOf returns the kind of err, classifying AWS API errors by their error code.
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.Kind != KindGeneral {
+		return appErr.Kind
+	}
+	if errors.Is(err, context.Canceled) {
+		return KindCancelled
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch code := apiErr.ErrorCode(); {
+		case code == "AccessDenied" || code == "AccessDeniedException" || code == "UnauthorizedOperation":
+			return KindPermissionDenied
+		case code == "ExpiredToken" || code == "ExpiredTokenException" || code == "InvalidClientTokenId" || code == "UnrecognizedClientException":
+			return KindAuthentication
+		case strings.HasSuffix(code, "NotFound") || strings.HasSuffix(code, "NotFoundException") || code == "InvalidInstanceId":
+			return KindNotFound
+		}
+	}
+	return KindGeneral
+}
+
+// ExitCode returns the exit code for err.
+func ExitCode(err error) int {
+	switch KindOf(err) {
+	case KindInvalidInput:
+		return ExitInvalidInput
+	case KindNotFound:
+		return ExitNotFound
+	case KindPermissionDenied:
+		return ExitPermissionDenied
+	case KindAuthentication:
+		return ExitAuthentication
+	case KindCancelled:
+		return ExitCancelled
+	default:
+		return ExitGeneral
+	}
+}
+
+// hints suggest fixes for AWS error codes.
+var hints = map[string]string{
+	"ExpiredToken":                "The AWS credentials have expired. Run eclogin again without --no-input to log in, or refresh the credentials of the profile.",
+	"ExpiredTokenException":       "The AWS credentials have expired. Run eclogin again without --no-input to log in, or refresh the credentials of the profile.",
+	"InvalidClientTokenId":        "The AWS credentials are invalid. Check the access key of the profile.",
+	"UnrecognizedClientException": "The AWS credentials are invalid. Check the access key of the profile.",
+	"AccessDenied":                "The credentials lack an IAM permission. Check the policies of the user or role, or pick another --profile.",
+	"AccessDeniedException":       "The credentials lack an IAM permission. Check the policies of the user or role, or pick another --profile.",
+	"UnauthorizedOperation":       "The credentials lack an IAM permission. Check the policies of the user or role, or pick another --profile.",
+	"TargetNotConnected":          "The SSM agent of the target is not connected. Check that the agent is running and the instance profile allows Systems Manager.",
+	"InvalidInstanceId":           "The instance is not running or not managed by Systems Manager.",
+	"ClusterNotFoundException":    "The cluster does not exist in this account and region. Check --region and --profile.",
+	"RequestExpired":              "The request was signed too long ago. Check that the system clock is correct.",
+	"SignatureDoesNotMatch":       "The request signature is invalid. Check the secret key of the profile and the system clock.",
+	"ThrottlingException":         "AWS is throttling the requests. Wait a moment and try again.",
+	"RequestLimitExceeded":        "AWS is throttling the requests. Wait a moment and try again.",
+}
+
+const executeCommandHint = "ECS Exec is not enabled for the task. Enable enableExecuteCommand on the service and start a new task."
+
+// Hint returns a suggestion to fix err, or an empty string when there is none.
+func Hint(err error) string {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	if apiErr.ErrorCode() == "InvalidParameterException" && strings.Contains(strings.ToLower(apiErr.ErrorMessage()), "execute command") {
+		return executeCommandHint
+	}
+	return hints[apiErr.ErrorCode()]
+}
@@ -0,0 +1,67 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"plain error", errors.New("boom"), ExitGeneral},
+		{"not found", NotFound("no instances found"), ExitNotFound},
+		{"wrapped invalid input", fmt.Errorf("outer: %w", InvalidInput("bad flag")), ExitInvalidInput},
+		{"cancelled", Wrap(ErrCancelled, "select cluster"), ExitCancelled},
+		{"context cancelled", context.Canceled, ExitCancelled},
+		{"access denied", &smithy.GenericAPIError{Code: "AccessDeniedException"}, ExitPermissionDenied},
+		{"expired token", Wrap(&smithy.GenericAPIError{Code: "ExpiredToken"}, "list clusters"), ExitAuthentication},
+		{"cluster not found", &smithy.GenericAPIError{Code: "ClusterNotFoundException"}, ExitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ExitCode(tt.err); code != tt.expected {
+				t.Errorf("expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(nil, "ignored") != nil {
+		t.Error("expected nil for a nil error")
+	}
+
+	err := Wrap(NotFound("no tasks"), "failed to get ECS task ID")
+	if err.Error() != "failed to get ECS task ID: no tasks" {
+		t.Errorf("unexpected message: %q", err.Error())
+	}
+	if KindOf(err) != KindNotFound {
+		t.Errorf("expected the kind to be kept, got %v", KindOf(err))
+	}
+}
+
+func TestHint(t *testing.T) {
+	if hint := Hint(&smithy.GenericAPIError{Code: "TargetNotConnected"}); hint != hints["TargetNotConnected"] {
+		t.Errorf("unexpected hint: %q", hint)
+	}
+
+	execErr := &smithy.GenericAPIError{
+		Code:    "InvalidParameterException",
+		Message: "The execute command failed because execute command was not enabled when the task was run.",
+	}
+	if hint := Hint(execErr); hint != executeCommandHint {
+		t.Errorf("unexpected hint: %q", hint)
+	}
+
+	if hint := Hint(errors.New("boom")); hint != "" {
+		t.Errorf("expected no hint, got %q", hint)
+	}
+}
@@ -0,0 +1,111 @@
+// Package audit keeps a local JSON lines log of the sessions started by eclogin,
+// optionally forwarded to a syslog server or an HTTP endpoint.
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	EventSessionStart = "session_start"
+	EventSessionEnd   = "session_end"
+)
+
+// Event is the start or end of a session.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Type      string    `json:"type"`
+	CallerARN string    `json:"caller_arn,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	Account   string    `json:"account,omitempty"`
+	Region    string    `json:"region,omitempty"`
+	Target    string    `json:"target"`
+	SessionID string    `json:"session_id,omitempty"`
+	// Duration and ExitCode are only set on session_end.
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	ExitCode        *int    `json:"exit_code,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Forwarder sends an event, encoded as a JSON line, to a remote collector.
+type Forwarder interface {
+	Forward(line []byte) error
+}
+
+// Logger appends events to a file and forwards them.
+type Logger struct {
+	path       string
+	forwarders []Forwarder
+}
+
+func NewLogger(path string, forwarders ...Forwarder) *Logger {
+	return &Logger{path: path, forwarders: forwarders}
+}
+
+// Log writes the event to the file and every forwarder. All of them are tried;
+// the errors of those that fail are returned together.
+func (l *Logger) Log(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	errs := []error{l.append(line)}
+	for _, forwarder := range l.forwarders {
+		errs = append(errs, forwarder.Forward(line))
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Logger) append(line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// ExitStatusError reports the non-zero exit code of a session that does not run as a local process.
+type ExitStatusError struct {
+	Code int
+}
+
+func (e *ExitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitStatusError) ExitCode() int {
+	return e.Code
+}
+
+// ExitCode returns the exit code of a session that ended with err, taken from an
+// *exec.ExitError or *ExitStatusError when there is one.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
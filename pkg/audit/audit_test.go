@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingForwarder struct {
+	lines [][]byte
+	err   error
+}
+
+func (f *recordingForwarder) Forward(line []byte) error {
+	f.lines = append(f.lines, line)
+	return f.err
+}
+
+func TestLoggerLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	forwarder := &recordingForwarder{}
+	logger := NewLogger(path, forwarder)
+
+	exitCode := 0
+	events := []Event{
+		{Event: EventSessionStart, Type: "ec2", Account: "123456789012", Target: "i-0123456789abcdef0", SessionID: "user-0abc"},
+		{Event: EventSessionEnd, Type: "ec2", Account: "123456789012", Target: "i-0123456789abcdef0", SessionID: "user-0abc", DurationSeconds: 12.5, ExitCode: &exitCode},
+	}
+	for _, event := range events {
+		if err := logger.Log(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+
+	var end Event
+	if err := json.Unmarshal([]byte(lines[1]), &end); err != nil {
+		t.Fatal(err)
+	}
+	if end.Event != EventSessionEnd || end.ExitCode == nil || *end.ExitCode != 0 || end.Time.IsZero() {
+		t.Errorf("unexpected event: %+v", end)
+	}
+	if len(forwarder.lines) != 2 || string(forwarder.lines[1]) != lines[1] {
+		t.Errorf("expected the forwarder to receive the same lines, got %q", forwarder.lines)
+	}
+}
+
+func TestLoggerLogForwardError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger := NewLogger(path, &recordingForwarder{err: errors.New("unreachable")})
+
+	if err := logger.Log(Event{Event: EventSessionStart, Target: "i-0123456789abcdef0"}); err == nil {
+		t.Error("expected the forwarder error to be returned")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the event to be written despite the forwarder error: %v", err)
+	}
+}
+
+func TestHTTPForwarder(t *testing.T) {
+	var body, token, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		token = r.Header.Get("Authorization")
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	forwarder := NewHTTPForwarder(server.URL, map[string]string{"Authorization": "Bearer secret"})
+	if err := forwarder.Forward([]byte(`{"event":"session_start"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"event":"session_start"}` || token != "Bearer secret" || contentType != "application/json" {
+		t.Errorf("unexpected request: body=%q token=%q content-type=%q", body, token, contentType)
+	}
+}
+
+func TestHTTPForwarderStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if err := NewHTTPForwarder(server.URL, nil).Forward([]byte(`{}`)); err == nil {
+		t.Error("expected an error for a 403 response")
+	}
+}
+
+func TestSyslogMessage(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	hostname, _ := os.Hostname()
+
+	message := string(NewSyslogForwarder("", "localhost:514").message([]byte(`{"event":"session_start"}`), now))
+	expected := fmt.Sprintf(`<86>1 2026-01-02T03:04:05Z %s eclogin %d - - {"event":"session_start"}`, hostname, os.Getpid())
+	if message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+
+	message = string(NewSyslogForwarder("tcp", "localhost:514").message([]byte(`{}`), now))
+	if !strings.HasSuffix(message, "\n") {
+		t.Errorf("expected TCP messages to end with a newline, got %q", message)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != 0 {
+		t.Errorf("expected 0, got %d", code)
+	}
+	if code := ExitCode(fmt.Errorf("session: %w", &ExitStatusError{Code: 127})); code != 127 {
+		t.Errorf("expected 127, got %d", code)
+	}
+	if code := ExitCode(errors.New("boom")); code != 1 {
+		t.Errorf("expected 1, got %d", code)
+	}
+}
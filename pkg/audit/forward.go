@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	forwardTimeout = 5 * time.Second
+
+	// syslogPriority is facility authpriv (10) with severity info (6), as in RFC 5424.
+	syslogPriority = 10*8 + 6
+	syslogAppName  = "eclogin"
+)
+
+// SyslogForwarder sends events as RFC 5424 messages over UDP or TCP.
+// TCP messages are terminated by a newline (RFC 6587 non-transparent framing).
+type SyslogForwarder struct {
+	Network string
+	Address string
+}
+
+func NewSyslogForwarder(network, address string) *SyslogForwarder {
+	if network == "" {
+		network = "udp"
+	}
+	return &SyslogForwarder{Network: network, Address: address}
+}
+
+func (f *SyslogForwarder) Forward(line []byte) error {
+	conn, err := net.DialTimeout(f.Network, f.Address, forwardTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(f.message(line, time.Now())); err != nil {
+		return fmt.Errorf("failed to send to syslog: %w", err)
+	}
+	return nil
+}
+
+func (f *SyslogForwarder) message(line []byte, now time.Time) []byte {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	message := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		syslogPriority, now.Format(time.RFC3339), hostname, syslogAppName, os.Getpid(), line)
+	if f.Network != "udp" {
+		message += "\n"
+	}
+	return []byte(message)
+}
+
+// HTTPForwarder posts every event as a JSON document.
+type HTTPForwarder struct {
+	URL     string
+	Headers map[string]string
+	client  *http.Client
+}
+
+func NewHTTPForwarder(url string, headers map[string]string) *HTTPForwarder {
+	return &HTTPForwarder{URL: url, Headers: headers, client: &http.Client{Timeout: forwardTimeout}}
+}
+
+func (f *HTTPForwarder) Forward(line []byte) error {
+	req, err := http.NewRequest(http.MethodPost, f.URL, bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range f.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send audit event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send audit event: %s returned %s", f.URL, resp.Status)
+	}
+	return nil
+}
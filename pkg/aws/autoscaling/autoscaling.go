@@ -0,0 +1,61 @@
+package autoscaling
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+)
+
+const (
+	LifecycleStateInService = string(types.LifecycleStateInService)
+	HealthStatusHealthy     = "Healthy"
+)
+
+type AutoScalingClient interface {
+	DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+}
+
+// Instance is a member of an Auto Scaling group.
+type Instance struct {
+	ID             string
+	LifecycleState string
+	HealthStatus   string
+}
+
+// State returns the lifecycle state and health status of the instance, such as InService/Healthy.
+func (i Instance) State() string {
+	return fmt.Sprintf("%s/%s", i.LifecycleState, i.HealthStatus)
+}
+
+// ListInServiceInstances returns the healthy instances of the group that are in service, sorted by ID.
+func ListInServiceInstances(ctx context.Context, client AutoScalingClient, groupName string) ([]Instance, error) {
+	output, err := client.DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []string{groupName},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe Auto Scaling group %s: %w", groupName, err)
+	}
+	if len(output.AutoScalingGroups) == 0 {
+		return nil, apperr.NotFound("Auto Scaling group %s not found", groupName)
+	}
+
+	var instances []Instance
+	for _, instance := range output.AutoScalingGroups[0].Instances {
+		member := Instance{
+			ID:             aws.ToString(instance.InstanceId),
+			LifecycleState: string(instance.LifecycleState),
+			HealthStatus:   aws.ToString(instance.HealthStatus),
+		}
+		if member.LifecycleState == LifecycleStateInService && member.HealthStatus == HealthStatusHealthy {
+			instances = append(instances, member)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+
+	return instances, nil
+}
@@ -0,0 +1,49 @@
+package autoscaling
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+)
+
+type mockAutoScalingClient struct {
+	groups []types.AutoScalingGroup
+}
+
+func (m *mockAutoScalingClient) DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: m.groups}, nil
+}
+
+func TestListInServiceInstances(t *testing.T) {
+	client := &mockAutoScalingClient{groups: []types.AutoScalingGroup{{
+		AutoScalingGroupName: aws.String("web"),
+		Instances: []types.Instance{
+			{InstanceId: aws.String("i-3"), LifecycleState: types.LifecycleStateInService, HealthStatus: aws.String("Healthy")},
+			{InstanceId: aws.String("i-2"), LifecycleState: types.LifecycleStatePending, HealthStatus: aws.String("Healthy")},
+			{InstanceId: aws.String("i-4"), LifecycleState: types.LifecycleStateInService, HealthStatus: aws.String("Unhealthy")},
+			{InstanceId: aws.String("i-1"), LifecycleState: types.LifecycleStateInService, HealthStatus: aws.String("Healthy")},
+		},
+	}}}
+
+	instances, err := ListInServiceInstances(context.Background(), client, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 || instances[0].ID != "i-1" || instances[1].ID != "i-3" {
+		t.Fatalf("expected i-1 and i-3, got %+v", instances)
+	}
+	if instances[0].State() != "InService/Healthy" {
+		t.Errorf("unexpected state %s", instances[0].State())
+	}
+}
+
+func TestListInServiceInstancesNotFound(t *testing.T) {
+	_, err := ListInServiceInstances(context.Background(), &mockAutoScalingClient{}, "web")
+	if apperr.KindOf(err) != apperr.KindNotFound {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
@@ -2,25 +2,74 @@ package config
 
 import (
 	"context"
+	"eclogin/pkg/aws/sso"
+	"eclogin/pkg/prompt"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 )
 
-func LoadConfig(region, profile string) (aws.Config, error) {
+var (
+	endpointURL string
+	timeout     time.Duration
+)
+
+// SetEndpointURL makes every client created from LoadConfig send requests to url,
+// unless a service specific endpoint is configured (e.g. AWS_ENDPOINT_URL_SSM).
+func SetEndpointURL(url string) {
+	endpointURL = url
+}
+
+// SetTimeout limits every HTTP request of the clients created from LoadConfig to d.
+// Zero disables the limit.
+func SetTimeout(d time.Duration) {
+	timeout = d
+}
+
+func LoadConfig(ctx context.Context, region, profile string) (aws.Config, error) {
 	opts := []func(*config.LoadOptions) error{
 		config.WithRegion(region),
+		withMFATokenProvider(profile),
 	}
 
 	if profile != "" {
 		opts = append(opts, config.WithSharedConfigProfile(profile))
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(), opts...)
+	if endpointURL != "" {
+		opts = append(opts, config.WithBaseEndpoint(endpointURL))
+	}
+
+	// A per-request limit leaves the time spent on MFA and SSO prompts out of it.
+	if timeout > 0 {
+		opts = append(opts, config.WithHTTPClient(awshttp.NewBuildableClient().WithTimeout(timeout)))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
 		return aws.Config{}, fmt.Errorf("unable to load AWS config: %w", err)
 	}
 
+	// The SSO login needs the user to approve it in the browser, so it is skipped when prompting is disabled.
+	if prompt.Interactive() {
+		if err := sso.EnsureLogin(ctx, cfg, profile); err != nil {
+			return aws.Config{}, fmt.Errorf("unable to log in with AWS SSO: %w", err)
+		}
+		if cfg.Credentials != nil {
+			cfg.Credentials = aws.NewCredentialsCache(sso.NewLoginRetryProvider(cfg, profile, cfg.Credentials))
+		}
+	}
+
+	if sharedConfig, ok := assumedRole(ctx, profile); ok {
+		provider, err := newFileCredentialsProvider(sharedConfig, cfg.Credentials)
+		if err != nil {
+			return aws.Config{}, fmt.Errorf("unable to set up credentials cache: %w", err)
+		}
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
 	return cfg, nil
 }
@@ -1,6 +1,7 @@
 package config_test
 
 import (
+	"context"
 	"eclogin/pkg/aws/config"
 	"os"
 	"testing"
@@ -17,7 +18,7 @@ func TestLoadConfig(t *testing.T) {
 	region := "ap-northeast-1"
 	profile := "default"
 
-	cfg, err := config.LoadConfig(region, profile)
+	cfg, err := config.LoadConfig(context.Background(), region, profile)
 	if err != nil {
 		t.Fatalf("failed to load config: %v", err)
 	}
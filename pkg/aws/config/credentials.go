@@ -0,0 +1,166 @@
+package config
+
+import (
+	"context"
+	"crypto/sha1"
+	"eclogin/pkg/prompt"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	// credentialsExpiryWindow is how long before expiry cached credentials are considered stale.
+	credentialsExpiryWindow = time.Minute
+
+	roleSessionName = "eclogin"
+)
+
+// MFAPrompter asks for the MFA code when a profile assumes a role with mfa_serial.
+var MFAPrompter prompt.Prompter = prompt.NewUIPrompter()
+
+var credentialsCacheMu sync.Mutex
+
+func mfaTokenProvider(profile string) func() (string, error) {
+	return func() (string, error) {
+		return MFAPrompter.Input(fmt.Sprintf("Please enter MFA code for profile %s", profile), "")
+	}
+}
+
+func withMFATokenProvider(profile string) func(*config.LoadOptions) error {
+	return config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+		o.TokenProvider = mfaTokenProvider(profile)
+	})
+}
+
+// assumedRole returns the shared config of the profile and whether it gets its credentials from sts:AssumeRole.
+func assumedRole(ctx context.Context, profile string) (config.SharedConfig, bool) {
+	sharedConfig, err := config.LoadSharedConfigProfile(ctx, resolveProfile(profile))
+	if err != nil {
+		return config.SharedConfig{}, false
+	}
+	return sharedConfig, sharedConfig.RoleARN != ""
+}
+
+// credentialsCacheKey identifies the credentials of the role the profile assumes. Like the cache key of
+// the AWS CLI, it covers the settings of the role, so that a changed role_arn, source_profile or mfa_serial
+// does not return the credentials of the previous role.
+func credentialsCacheKey(sharedConfig config.SharedConfig) string {
+	data, _ := json.Marshal([]string{
+		sharedConfig.Profile,
+		sharedConfig.RoleARN,
+		sharedConfig.SourceProfileName,
+		sharedConfig.CredentialSource,
+		sharedConfig.MFASerial,
+		sharedConfig.ExternalID,
+		sharedConfig.RoleSessionName,
+	})
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func resolveProfile(profile string) string {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	return profile
+}
+
+// fileCredentialsProvider keeps temporary credentials in a 0600 file until they expire,
+// so that an MFA code is not asked for on every run.
+type fileCredentialsProvider struct {
+	path     string
+	provider aws.CredentialsProvider
+}
+
+func newFileCredentialsProvider(sharedConfig config.SharedConfig, provider aws.CredentialsProvider) (*fileCredentialsProvider, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileCredentialsProvider{
+		path:     filepath.Join(dir, "eclogin", "credentials", credentialsCacheKey(sharedConfig)+".json"),
+		provider: provider,
+	}, nil
+}
+
+func (p *fileCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	credentialsCacheMu.Lock()
+	defer credentialsCacheMu.Unlock()
+
+	if creds, err := p.read(); err == nil && creds.Expires.After(time.Now().Add(credentialsExpiryWindow)) {
+		return creds, nil
+	}
+
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	if creds.CanExpire {
+		if err := p.write(creds); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to cache credentials: %v\n", err)
+		}
+	}
+	return creds, nil
+}
+
+func (p *fileCredentialsProvider) read() (aws.Credentials, error) {
+	var creds aws.Credentials
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return creds, err
+	}
+	err = json.Unmarshal(data, &creds)
+	return creds, err
+}
+
+func (p *fileCredentialsProvider) write(creds aws.Credentials) error {
+	if err := os.MkdirAll(filepath.Dir(p.path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, data, 0600)
+}
+
+// AssumeRoleConfig returns a copy of cfg whose credentials come from assuming roleName in accountID.
+func AssumeRoleConfig(cfg aws.Config, accountID, roleName string) aws.Config {
+	roleARN := fmt.Sprintf("arn:%s:iam::%s:role/%s", Partition(cfg.Region), accountID, roleName)
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = roleSessionName
+	})
+
+	assumed := cfg.Copy()
+	assumed.Credentials = aws.NewCredentialsCache(provider)
+	return assumed
+}
+
+// Partition returns the ARN partition of the region.
+func Partition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	default:
+		return "aws"
+	}
+}
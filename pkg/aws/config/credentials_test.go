@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	p.calls++
+	return aws.Credentials{
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		SessionToken:    "TOKEN",
+		CanExpire:       true,
+		Expires:         time.Now().Add(time.Hour),
+	}, nil
+}
+
+func TestFileCredentialsProvider(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	upstream := &countingProvider{}
+	provider, err := newFileCredentialsProvider(config.SharedConfig{Profile: "test", RoleARN: "arn:aws:iam::123456789012:role/admin"}, upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		creds, err := provider.Retrieve(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if creds.AccessKeyID != "AKID" {
+			t.Errorf("expected AKID, got %s", creds.AccessKeyID)
+		}
+	}
+
+	if upstream.calls != 1 {
+		t.Errorf("expected credentials to be retrieved once, got %d", upstream.calls)
+	}
+}
+
+func TestCredentialsCacheKey(t *testing.T) {
+	base := config.SharedConfig{Profile: "prod", RoleARN: "arn:aws:iam::123456789012:role/admin", SourceProfileName: "default", MFASerial: "arn:aws:iam::123456789012:mfa/user"}
+	if credentialsCacheKey(base) != credentialsCacheKey(base) {
+		t.Error("expected the same key for the same settings")
+	}
+
+	changes := map[string]func(*config.SharedConfig){
+		"role_arn":       func(c *config.SharedConfig) { c.RoleARN = "arn:aws:iam::123456789012:role/readonly" },
+		"source_profile": func(c *config.SharedConfig) { c.SourceProfileName = "other" },
+		"mfa_serial":     func(c *config.SharedConfig) { c.MFASerial = "" },
+	}
+	for name, change := range changes {
+		changed := base
+		change(&changed)
+		if credentialsCacheKey(changed) == credentialsCacheKey(base) {
+			t.Errorf("expected a different key when %s changes", name)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	tests := map[string]string{
+		"ap-northeast-1": "aws",
+		"cn-north-1":     "aws-cn",
+		"us-gov-west-1":  "aws-us-gov",
+	}
+
+	for region, expected := range tests {
+		if partition := Partition(region); partition != expected {
+			t.Errorf("Partition(%s) = %s, want %s", region, partition, expected)
+		}
+	}
+}
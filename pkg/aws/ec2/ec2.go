@@ -2,36 +2,165 @@ package ec2
 
 import (
 	"context"
+	"eclogin/pkg/apperr"
 	"fmt"
-	"log"
+	"sort"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-func GetInstanceNameIDMap(client *ec2.Client) map[string]string {
-	instances, err := client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{})
+type EC2Client interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+}
+
+// InstanceStateClient starts and stops instances.
+type InstanceStateClient interface {
+	ec2.DescribeInstancesAPIClient
+	StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
+	StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
+}
+
+type Instance struct {
+	ID         string
+	Name       string
+	State      string
+	LaunchTime time.Time
+}
+
+func (i Instance) DisplayName() string {
+	return fmt.Sprintf("%s(%s)", i.Name, i.ID)
+}
+
+func ListInstances(ctx context.Context, client EC2Client) ([]Instance, error) {
+	output, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{})
 	if err != nil {
-		log.Fatalf("Failed to describe EC2 instances: %v", err)
+		return nil, fmt.Errorf("failed to describe instances: %w", err)
 	}
+	return toInstances(output), nil
+}
 
-	reservations := instances.Reservations
-	if len(reservations) == 0 {
-		log.Fatalf("No EC2 instances found")
+// ListInstancesByID returns the instances with the given IDs, such as the members of an Auto Scaling group.
+func ListInstancesByID(ctx context.Context, client ec2.DescribeInstancesAPIClient, instanceIDs []string) ([]Instance, error) {
+	output, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: instanceIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instances: %w", err)
 	}
+	return toInstances(output), nil
+}
 
-	instanceMap := make(map[string]string)
-	for _, reservation := range reservations {
+func toInstances(output *ec2.DescribeInstancesOutput) []Instance {
+	var instances []Instance
+	for _, reservation := range output.Reservations {
 		for _, instance := range reservation.Instances {
-			name := getInstanceName(instance.Tags)
-			displayName := fmt.Sprintf("%s(%s)", name, *instance.InstanceId)
-			instanceMap[displayName] = *instance.InstanceId
+			var state string
+			if instance.State != nil {
+				state = string(instance.State.Name)
+			}
+			instances = append(instances, Instance{
+				ID:         aws.ToString(instance.InstanceId),
+				Name:       getInstanceName(instance.Tags),
+				State:      state,
+				LaunchTime: aws.ToTime(instance.LaunchTime),
+			})
 		}
 	}
 
+	return instances
+}
+
+func GetInstanceNameIDMap(ctx context.Context, client EC2Client) (map[string]string, error) {
+	instances, err := ListInstances(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe EC2 instances: %w", err)
+	}
+
+	if len(instances) == 0 {
+		return nil, apperr.NotFound("no EC2 instances found")
+	}
+
+	return NameIDMap(instances), nil
+}
+
+// NameIDMap maps the display names of the instances to their IDs.
+func NameIDMap(instances []Instance) map[string]string {
+	instanceMap := make(map[string]string)
+	for _, instance := range instances {
+		instanceMap[instance.DisplayName()] = instance.ID
+	}
 	return instanceMap
 }
+
+// ListRegions returns the regions enabled for the account, sorted by name.
+func ListRegions(ctx context.Context, client EC2Client) ([]string, error) {
+	output, err := client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe regions: %w", err)
+	}
+
+	regions := make([]string, 0, len(output.Regions))
+	for _, region := range output.Regions {
+		regions = append(regions, aws.ToString(region.RegionName))
+	}
+	sort.Strings(regions)
+
+	return regions, nil
+}
+
+// GetInstanceState returns the state of the instance, such as running or stopped.
+func GetInstanceState(ctx context.Context, client ec2.DescribeInstancesAPIClient, instanceID string) (string, error) {
+	output, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe instance %s: %w", instanceID, err)
+	}
+
+	for _, reservation := range output.Reservations {
+		for _, instance := range reservation.Instances {
+			if instance.State != nil {
+				return string(instance.State.Name), nil
+			}
+		}
+	}
+	return "", apperr.NotFound("instance %s not found", instanceID)
+}
+
+// StartInstance starts a stopped instance without waiting for it to run.
+func StartInstance(ctx context.Context, client InstanceStateClient, instanceID string) error {
+	if _, err := client.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{instanceID}}); err != nil {
+		return fmt.Errorf("failed to start instance %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+// WaitUntilRunning waits until a pending instance is running.
+func WaitUntilRunning(ctx context.Context, client ec2.DescribeInstancesAPIClient, instanceID string, maxWait time.Duration) error {
+	waiter := ec2.NewInstanceRunningWaiter(client)
+	if err := waiter.Wait(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}}, maxWait); err != nil {
+		return fmt.Errorf("failed to wait for instance %s to run: %w", instanceID, err)
+	}
+	return nil
+}
+
+// WaitUntilStopped waits until a stopping instance has stopped, so that it can be started again.
+func WaitUntilStopped(ctx context.Context, client ec2.DescribeInstancesAPIClient, instanceID string, maxWait time.Duration) error {
+	waiter := ec2.NewInstanceStoppedWaiter(client)
+	if err := waiter.Wait(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}}, maxWait); err != nil {
+		return fmt.Errorf("failed to wait for instance %s to stop: %w", instanceID, err)
+	}
+	return nil
+}
+
+// StopInstance stops the instance without waiting for it to stop.
+func StopInstance(ctx context.Context, client InstanceStateClient, instanceID string) error {
+	if _, err := client.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{instanceID}}); err != nil {
+		return fmt.Errorf("failed to stop instance %s: %w", instanceID, err)
+	}
+	return nil
+}
+
 func getInstanceName(tags []types.Tag) string {
 	for _, tag := range tags {
 		if aws.ToString(tag.Key) == "Name" {
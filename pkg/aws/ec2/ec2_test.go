@@ -1,12 +1,41 @@
 package ec2
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
+type mockEC2Client struct{}
+
+func (m *mockEC2Client) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{
+			{
+				Instances: []types.Instance{
+					{
+						InstanceId: aws.String("i-123"),
+						Tags:       []types.Tag{{Key: aws.String("Name"), Value: aws.String("test1")}},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func (m *mockEC2Client) DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	return &ec2.DescribeRegionsOutput{
+		Regions: []types.Region{
+			{RegionName: aws.String("us-east-1")},
+			{RegionName: aws.String("ap-northeast-1")},
+		},
+	}, nil
+}
+
 func Test_getInstanceName(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -79,3 +108,91 @@ func TestGetInstanceDisplayNames(t *testing.T) {
 		}
 	}
 }
+
+func TestListInstances(t *testing.T) {
+	instances, err := ListInstances(context.Background(), &mockEC2Client{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(instances) != 1 || instances[0].DisplayName() != "test1(i-123)" {
+		t.Errorf("expected test1(i-123), got %v", instances)
+	}
+}
+
+func TestListInstancesByID(t *testing.T) {
+	client := &mockInstanceStateClient{state: types.InstanceStateNameRunning}
+	instances, err := ListInstancesByID(context.Background(), client, []string{"i-456"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(instances) != 1 || instances[0].ID != "i-456" || instances[0].State != "running" {
+		t.Errorf("expected running i-456, got %v", instances)
+	}
+}
+
+func TestListRegions(t *testing.T) {
+	regions, err := ListRegions(context.Background(), &mockEC2Client{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(regions) != 2 || regions[0] != "ap-northeast-1" || regions[1] != "us-east-1" {
+		t.Errorf("expected sorted regions, got %v", regions)
+	}
+}
+
+type mockInstanceStateClient struct {
+	state   types.InstanceStateName
+	started []string
+	stopped []string
+}
+
+func (m *mockInstanceStateClient) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{
+			{
+				Instances: []types.Instance{
+					{
+						InstanceId: aws.String(params.InstanceIds[0]),
+						State:      &types.InstanceState{Name: m.state},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func (m *mockInstanceStateClient) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	m.started = append(m.started, params.InstanceIds...)
+	m.state = types.InstanceStateNameRunning
+	return &ec2.StartInstancesOutput{}, nil
+}
+
+func (m *mockInstanceStateClient) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	m.stopped = append(m.stopped, params.InstanceIds...)
+	m.state = types.InstanceStateNameStopping
+	return &ec2.StopInstancesOutput{}, nil
+}
+
+func TestStartAndStopInstance(t *testing.T) {
+	client := &mockInstanceStateClient{state: types.InstanceStateNameStopped}
+	ctx := context.Background()
+
+	state, err := GetInstanceState(ctx, client, "i-123")
+	if err != nil || state != "stopped" {
+		t.Fatalf("expected stopped, got %q (%v)", state, err)
+	}
+
+	if err := StartInstance(ctx, client, "i-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WaitUntilRunning(ctx, client, "i-123", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := StopInstance(ctx, client, "i-123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.started) != 1 || len(client.stopped) != 1 || client.started[0] != "i-123" {
+		t.Errorf("expected i-123 to be started and stopped once, got %v and %v", client.started, client.stopped)
+	}
+}
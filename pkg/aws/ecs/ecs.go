@@ -2,6 +2,7 @@ package ecs
 
 import (
 	"context"
+	"eclogin/pkg/apperr"
 	"fmt"
 	"strings"
 
@@ -17,72 +18,66 @@ type ECSClient interface {
 	ExecuteCommand(ctx context.Context, params *ecs.ExecuteCommandInput, optFns ...func(*ecs.Options)) (*ecs.ExecuteCommandOutput, error)
 }
 
-func ListClusters(c ECSClient) ([]string, error) {
-	resp, err := c.ListClusters(context.TODO(), &ecs.ListClustersInput{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list clusters: %w", err)
-	}
-
-	clusterARNs := resp.ClusterArns
-	if len(clusterARNs) == 0 {
-		return nil, fmt.Errorf("no clusters found")
-	}
-
-	clusters := make([]string, len(clusterARNs))
-	for i, arn := range clusterARNs {
-		clusters[i] = strings.Split(arn, "/")[1]
+// ListClusters returns the names of all clusters. It returns an empty list when there are none.
+func ListClusters(ctx context.Context, c ECSClient) ([]string, error) {
+	var clusters []string
+	paginator := ecs.NewListClustersPaginator(c, &ecs.ListClustersInput{})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list clusters: %w", err)
+		}
+		for _, arn := range resp.ClusterArns {
+			clusters = append(clusters, strings.Split(arn, "/")[1])
+		}
 	}
 
 	return clusters, nil
 }
 
-func ListServices(client ECSClient, clusterName string) ([]string, error) {
-	resp, err := client.ListServices(context.TODO(), &ecs.ListServicesInput{
+// ListServices returns the names of all services in the cluster. It returns an empty list when there are none.
+func ListServices(ctx context.Context, client ECSClient, clusterName string) ([]string, error) {
+	var services []string
+	paginator := ecs.NewListServicesPaginator(client, &ecs.ListServicesInput{
 		Cluster:    aws.String(clusterName),
 		MaxResults: aws.Int32(100),
 	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list services: %w", err)
-	}
-
-	serviceARNs := resp.ServiceArns
-	if len(serviceARNs) == 0 {
-		return nil, fmt.Errorf("no services found in cluster %s", clusterName)
-	}
-
-	services := make([]string, len(serviceARNs))
-	for i, arn := range serviceARNs {
-		parts := strings.Split(arn, "/")
-		services[i] = parts[len(parts)-1]
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list services: %w", err)
+		}
+		for _, arn := range resp.ServiceArns {
+			parts := strings.Split(arn, "/")
+			services = append(services, parts[len(parts)-1])
+		}
 	}
 
 	return services, nil
 }
 
-func ListTaskIDs(client ECSClient, clusterName, serviceName string) ([]string, error) {
-	resp, err := client.ListTasks(context.TODO(), &ecs.ListTasksInput{
+// ListTaskIDs returns the IDs of the running tasks of the service. It returns an empty list when there are none.
+func ListTaskIDs(ctx context.Context, client ECSClient, clusterName, serviceName string) ([]string, error) {
+	var taskIDs []string
+	paginator := ecs.NewListTasksPaginator(client, &ecs.ListTasksInput{
 		Cluster:     aws.String(clusterName),
 		ServiceName: aws.String(serviceName),
 	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list tasks: %w", err)
-	}
-
-	taskARNs := resp.TaskArns
-	if len(taskARNs) == 0 {
-		return nil, fmt.Errorf("no tasks found for service %s in cluster %s", serviceName, clusterName)
-	}
-
-	taskIDs := make([]string, len(taskARNs))
-	for i, arn := range taskARNs {
-		taskIDs[i] = strings.Split(arn, "/")[2]
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tasks: %w", err)
+		}
+		for _, arn := range resp.TaskArns {
+			taskIDs = append(taskIDs, strings.Split(arn, "/")[2])
+		}
 	}
 
 	return taskIDs, nil
 }
 
-func GetContainerInfo(client ECSClient, clusterName, taskID string) (map[string]string, error) {
-	resp, err := client.DescribeTasks(context.TODO(), &ecs.DescribeTasksInput{
+func GetContainerInfo(ctx context.Context, client ECSClient, clusterName, taskID string) (map[string]string, error) {
+	resp, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
 		Tasks:   []string{taskID},
 		Cluster: aws.String(clusterName),
 	})
@@ -90,6 +85,10 @@ func GetContainerInfo(client ECSClient, clusterName, taskID string) (map[string]
 		return nil, fmt.Errorf("failed to describe tasks: %w", err)
 	}
 
+	if len(resp.Tasks) == 0 {
+		return nil, apperr.NotFound("task %s not found in cluster %s", taskID, clusterName)
+	}
+
 	containerInfo := make(map[string]string)
 	for _, container := range resp.Tasks[0].Containers {
 		containerInfo[*container.Name] = strings.Split(*container.RuntimeId, "-")[0]
@@ -98,6 +97,19 @@ func GetContainerInfo(client ECSClient, clusterName, taskID string) (map[string]
 	return containerInfo, nil
 }
 
+// IsTaskRunning reports whether the task still exists and is in the RUNNING state.
+func IsTaskRunning(ctx context.Context, client ECSClient, clusterName, taskID string) (bool, error) {
+	resp, err := client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+		Tasks:   []string{taskID},
+		Cluster: aws.String(clusterName),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to describe tasks: %w", err)
+	}
+
+	return len(resp.Tasks) > 0 && aws.ToString(resp.Tasks[0].LastStatus) == "RUNNING", nil
+}
+
 func ListContainerNames(containerInfo map[string]string) []string {
 	containers := make([]string, 0, len(containerInfo))
 	for name := range containerInfo {
@@ -106,8 +118,8 @@ func ListContainerNames(containerInfo map[string]string) []string {
 	return containers
 }
 
-func ExecuteContainerCommand(client ECSClient, command, taskID, clusterName, containerName string) (*ecs.ExecuteCommandOutput, error) {
-	output, err := client.ExecuteCommand(context.TODO(), &ecs.ExecuteCommandInput{
+func ExecuteContainerCommand(ctx context.Context, client ECSClient, command, taskID, clusterName, containerName string) (*ecs.ExecuteCommandOutput, error) {
+	output, err := client.ExecuteCommand(ctx, &ecs.ExecuteCommandInput{
 		Command:     aws.String(command),
 		Interactive: true,
 		Task:        aws.String(taskID),
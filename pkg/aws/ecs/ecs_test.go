@@ -4,11 +4,15 @@ import (
 	"context"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 )
 
-type mockECSClient struct{}
+type mockECSClient struct {
+	// servicePages, when set, are returned as the pages of ListServices.
+	servicePages [][]string
+}
 
 func (m *mockECSClient) ListClusters(ctx context.Context, params *ecs.ListClustersInput, optFns ...func(*ecs.Options)) (*ecs.ListClustersOutput, error) {
 	return &ecs.ListClustersOutput{
@@ -17,6 +21,20 @@ func (m *mockECSClient) ListClusters(ctx context.Context, params *ecs.ListCluste
 }
 
 func (m *mockECSClient) ListServices(ctx context.Context, params *ecs.ListServicesInput, optFns ...func(*ecs.Options)) (*ecs.ListServicesOutput, error) {
+	if m.servicePages != nil {
+		page := 0
+		if params.NextToken != nil {
+			page = 1
+		}
+		output := &ecs.ListServicesOutput{}
+		for _, name := range m.servicePages[page] {
+			output.ServiceArns = append(output.ServiceArns, "arn:aws:ecs:region:account-id:service/cluster/"+name)
+		}
+		if page+1 < len(m.servicePages) {
+			output.NextToken = aws.String("next")
+		}
+		return output, nil
+	}
 	return &ecs.ListServicesOutput{
 		ServiceArns: []string{"arn:aws:ecs:region:account-id:service/cluster/test-service"},
 	}, nil
@@ -34,6 +52,7 @@ func (m *mockECSClient) DescribeTasks(ctx context.Context, params *ecs.DescribeT
 	return &ecs.DescribeTasksOutput{
 		Tasks: []types.Task{
 			{
+				LastStatus: aws.String("RUNNING"),
 				Containers: []types.Container{
 					{
 						Name:      &containerName,
@@ -51,7 +70,7 @@ func (m *mockECSClient) ExecuteCommand(ctx context.Context, params *ecs.ExecuteC
 
 func TestListClusters(t *testing.T) {
 	client := &mockECSClient{}
-	clusters, _ := ListClusters(client)
+	clusters, _ := ListClusters(context.Background(), client)
 	if len(clusters) != 1 || clusters[0] != "test-cluster" {
 		t.Errorf("expected test-cluster, got %v", clusters)
 	}
@@ -59,15 +78,38 @@ func TestListClusters(t *testing.T) {
 
 func TestListServices(t *testing.T) {
 	client := &mockECSClient{}
-	services, _ := ListServices(client, "test-cluster")
+	services, _ := ListServices(context.Background(), client, "test-cluster")
 	if len(services) != 1 || services[0] != "test-service" {
 		t.Errorf("expected test-service, got %v", services)
 	}
 }
 
+func TestListServicesPages(t *testing.T) {
+	tests := []struct {
+		name     string
+		pages    [][]string
+		expected int
+	}{
+		{name: "all pages", pages: [][]string{{"api", "worker"}, {"batch"}}, expected: 3},
+		{name: "no services", pages: [][]string{{}}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, err := ListServices(context.Background(), &mockECSClient{servicePages: tt.pages}, "test-cluster")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(services) != tt.expected {
+				t.Errorf("expected %d services, got %v", tt.expected, services)
+			}
+		})
+	}
+}
+
 func TestListTaskIDs(t *testing.T) {
 	client := &mockECSClient{}
-	tasks, _ := ListTaskIDs(client, "test-cluster", "test-service")
+	tasks, _ := ListTaskIDs(context.Background(), client, "test-cluster", "test-service")
 	if len(tasks) != 1 || tasks[0] != "test-task" {
 		t.Errorf("expected test-task, got %v", tasks)
 	}
@@ -75,12 +117,20 @@ func TestListTaskIDs(t *testing.T) {
 
 func TestGetContainerInfo(t *testing.T) {
 	client := &mockECSClient{}
-	containerAndRuntimeIDs, _ := GetContainerInfo(client, "test-cluster", "test-task")
+	containerAndRuntimeIDs, _ := GetContainerInfo(context.Background(), client, "test-cluster", "test-task")
 	if len(containerAndRuntimeIDs) != 1 || containerAndRuntimeIDs["test-container"] != "test" {
 		t.Errorf("expected test, got %v", containerAndRuntimeIDs["test-container"])
 	}
 }
 
+func TestIsTaskRunning(t *testing.T) {
+	client := &mockECSClient{}
+	running, err := IsTaskRunning(context.Background(), client, "test-cluster", "test-task")
+	if err != nil || !running {
+		t.Errorf("expected running task, got %v (%v)", running, err)
+	}
+}
+
 func TestListContainerNames(t *testing.T) {
 	containerAndRuntimeIDs := map[string]string{"test-container": "test-runtime"}
 	containers := ListContainerNames(containerAndRuntimeIDs)
@@ -91,7 +141,7 @@ func TestListContainerNames(t *testing.T) {
 
 func TestExecuteContainerCommand(t *testing.T) {
 	client := &mockECSClient{}
-	output, _ := ExecuteContainerCommand(client, "/bin/bash", "test-task", "test-cluster", "test-container")
+	output, _ := ExecuteContainerCommand(context.Background(), client, "/bin/bash", "test-task", "test-cluster", "test-container")
 	if output == nil {
 		t.Error("expected non-nil output")
 	}
@@ -0,0 +1,84 @@
+package elbv2
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+const TargetHealthStateHealthy = string(types.TargetHealthStateEnumHealthy)
+
+type ELBClient interface {
+	DescribeTargetGroups(ctx context.Context, params *elasticloadbalancingv2.DescribeTargetGroupsInput, optFns ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeTargetGroupsOutput, error)
+	DescribeTargetHealth(ctx context.Context, params *elasticloadbalancingv2.DescribeTargetHealthInput, optFns ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeTargetHealthOutput, error)
+}
+
+// Target is an instance registered in a target group.
+type Target struct {
+	ID    string
+	Port  int32
+	State string
+}
+
+// ListHealthyTargets returns the healthy instances of the target group, given by name or ARN, sorted by ID.
+func ListHealthyTargets(ctx context.Context, client ELBClient, targetGroup string) ([]Target, error) {
+	group, err := describeTargetGroup(ctx, client, targetGroup)
+	if err != nil {
+		return nil, err
+	}
+	if group.TargetType != types.TargetTypeEnumInstance {
+		return nil, apperr.InvalidInput("target group %s has %s targets, not instances", targetGroup, group.TargetType)
+	}
+
+	output, err := client.DescribeTargetHealth(ctx, &elasticloadbalancingv2.DescribeTargetHealthInput{
+		TargetGroupArn: group.TargetGroupArn,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe target health of %s: %w", targetGroup, err)
+	}
+
+	var targets []Target
+	for _, description := range output.TargetHealthDescriptions {
+		if description.Target == nil || description.TargetHealth == nil {
+			continue
+		}
+		target := Target{
+			ID:    aws.ToString(description.Target.Id),
+			Port:  aws.ToInt32(description.Target.Port),
+			State: string(description.TargetHealth.State),
+		}
+		if target.State == TargetHealthStateHealthy {
+			targets = append(targets, target)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+
+	return targets, nil
+}
+
+func describeTargetGroup(ctx context.Context, client ELBClient, targetGroup string) (types.TargetGroup, error) {
+	input := &elasticloadbalancingv2.DescribeTargetGroupsInput{Names: []string{targetGroup}}
+	if strings.HasPrefix(targetGroup, "arn:") {
+		input = &elasticloadbalancingv2.DescribeTargetGroupsInput{TargetGroupArns: []string{targetGroup}}
+	}
+
+	output, err := client.DescribeTargetGroups(ctx, input)
+	var notFound *types.TargetGroupNotFoundException
+	if errors.As(err, &notFound) {
+		return types.TargetGroup{}, apperr.NotFound("target group %s not found", targetGroup)
+	}
+	if err != nil {
+		return types.TargetGroup{}, fmt.Errorf("failed to describe target group %s: %w", targetGroup, err)
+	}
+	if len(output.TargetGroups) == 0 {
+		return types.TargetGroup{}, apperr.NotFound("target group %s not found", targetGroup)
+	}
+	return output.TargetGroups[0], nil
+}
@@ -0,0 +1,68 @@
+package elbv2
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+const testTargetGroupARN = "arn:aws:elasticloadbalancing:ap-northeast-1:123456789012:targetgroup/web/0123456789abcdef"
+
+type mockELBClient struct {
+	targetType types.TargetTypeEnum
+	input      *elasticloadbalancingv2.DescribeTargetGroupsInput
+}
+
+func (m *mockELBClient) DescribeTargetGroups(ctx context.Context, params *elasticloadbalancingv2.DescribeTargetGroupsInput, optFns ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeTargetGroupsOutput, error) {
+	m.input = params
+	return &elasticloadbalancingv2.DescribeTargetGroupsOutput{
+		TargetGroups: []types.TargetGroup{{TargetGroupArn: aws.String(testTargetGroupARN), TargetType: m.targetType}},
+	}, nil
+}
+
+func (m *mockELBClient) DescribeTargetHealth(ctx context.Context, params *elasticloadbalancingv2.DescribeTargetHealthInput, optFns ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeTargetHealthOutput, error) {
+	return &elasticloadbalancingv2.DescribeTargetHealthOutput{
+		TargetHealthDescriptions: []types.TargetHealthDescription{
+			{Target: &types.TargetDescription{Id: aws.String("i-2"), Port: aws.Int32(80)}, TargetHealth: &types.TargetHealth{State: types.TargetHealthStateEnumHealthy}},
+			{Target: &types.TargetDescription{Id: aws.String("i-3"), Port: aws.Int32(80)}, TargetHealth: &types.TargetHealth{State: types.TargetHealthStateEnumDraining}},
+			{Target: &types.TargetDescription{Id: aws.String("i-1"), Port: aws.Int32(80)}, TargetHealth: &types.TargetHealth{State: types.TargetHealthStateEnumHealthy}},
+		},
+	}, nil
+}
+
+func TestListHealthyTargets(t *testing.T) {
+	tests := []struct {
+		targetGroup string
+		byARN       bool
+	}{
+		{targetGroup: "web"},
+		{targetGroup: testTargetGroupARN, byARN: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.targetGroup, func(t *testing.T) {
+			client := &mockELBClient{targetType: types.TargetTypeEnumInstance}
+			targets, err := ListHealthyTargets(context.Background(), client, tt.targetGroup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(targets) != 2 || targets[0].ID != "i-1" || targets[1].ID != "i-2" {
+				t.Errorf("expected i-1 and i-2, got %+v", targets)
+			}
+			if byARN := len(client.input.TargetGroupArns) == 1; byARN != tt.byARN {
+				t.Errorf("expected the target group to be looked up by ARN: %v, got %+v", tt.byARN, client.input)
+			}
+		})
+	}
+}
+
+func TestListHealthyTargetsNotInstances(t *testing.T) {
+	_, err := ListHealthyTargets(context.Background(), &mockELBClient{targetType: types.TargetTypeEnumIp}, "web")
+	if apperr.KindOf(err) != apperr.KindInvalidInput {
+		t.Errorf("expected an invalid input error, got %v", err)
+	}
+}
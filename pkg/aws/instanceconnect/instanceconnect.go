@@ -0,0 +1,64 @@
+// Package instanceconnect pushes ephemeral SSH keys to instances with EC2 Instance Connect.
+package instanceconnect
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2instanceconnect"
+	"golang.org/x/crypto/ssh"
+)
+
+type InstanceConnectClient interface {
+	SendSSHPublicKey(ctx context.Context, params *ec2instanceconnect.SendSSHPublicKeyInput, optFns ...func(*ec2instanceconnect.Options)) (*ec2instanceconnect.SendSSHPublicKeyOutput, error)
+}
+
+// KeyPair is an ed25519 key pair, with the private key in OpenSSH format
+// and the public key in authorized_keys format.
+type KeyPair struct {
+	PrivateKey []byte
+	PublicKey  string
+}
+
+// GenerateKeyPair generates a key pair whose public key carries comment.
+func GenerateKeyPair(comment string) (KeyPair, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(private, comment)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic))) + " " + comment
+
+	return KeyPair{PrivateKey: pem.EncodeToMemory(block), PublicKey: authorizedKey}, nil
+}
+
+// SendSSHPublicKey authorizes publicKey for osUser on the instance. The key is accepted
+// for 60 seconds, so the SSH connection must be made right after.
+func SendSSHPublicKey(ctx context.Context, client InstanceConnectClient, instanceID, osUser, publicKey string) error {
+	output, err := client.SendSSHPublicKey(ctx, &ec2instanceconnect.SendSSHPublicKeyInput{
+		InstanceId:     aws.String(instanceID),
+		InstanceOSUser: aws.String(osUser),
+		SSHPublicKey:   aws.String(publicKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send SSH public key: %w", err)
+	}
+	if !output.Success {
+		return fmt.Errorf("failed to send SSH public key to %s", instanceID)
+	}
+	return nil
+}
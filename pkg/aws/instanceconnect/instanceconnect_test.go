@@ -0,0 +1,57 @@
+package instanceconnect
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2instanceconnect"
+	"golang.org/x/crypto/ssh"
+)
+
+type mockInstanceConnectClient struct {
+	input *ec2instanceconnect.SendSSHPublicKeyInput
+}
+
+func (m *mockInstanceConnectClient) SendSSHPublicKey(ctx context.Context, params *ec2instanceconnect.SendSSHPublicKeyInput, optFns ...func(*ec2instanceconnect.Options)) (*ec2instanceconnect.SendSSHPublicKeyOutput, error) {
+	m.input = params
+	return &ec2instanceconnect.SendSSHPublicKeyOutput{Success: true}, nil
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	keyPair, err := GenerateKeyPair("eclogin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyPair.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to parse private key: %v", err)
+	}
+	public, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(keyPair.PublicKey))
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+
+	if public.Type() != ssh.KeyAlgoED25519 || comment != "eclogin" {
+		t.Errorf("unexpected public key: %s", keyPair.PublicKey)
+	}
+	if string(signer.PublicKey().Marshal()) != string(public.Marshal()) {
+		t.Error("expected the public key to match the private key")
+	}
+}
+
+func TestSendSSHPublicKey(t *testing.T) {
+	client := &mockInstanceConnectClient{}
+	publicKey := "ssh-ed25519 AAAA eclogin"
+	if err := SendSSHPublicKey(context.Background(), client, "i-0123456789abcdef0", "ec2-user", publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if aws.ToString(client.input.InstanceId) != "i-0123456789abcdef0" ||
+		aws.ToString(client.input.InstanceOSUser) != "ec2-user" ||
+		!strings.HasPrefix(aws.ToString(client.input.SSHPublicKey), "ssh-ed25519 ") {
+		t.Errorf("unexpected input: %+v", client.input)
+	}
+}
@@ -0,0 +1,48 @@
+package organizations
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+type OrganizationsClient interface {
+	ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error)
+}
+
+type Account struct {
+	ID   string
+	Name string
+}
+
+// ListAccounts returns the active accounts of the organization.
+func ListAccounts(ctx context.Context, client OrganizationsClient) ([]Account, error) {
+	var accounts []Account
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list accounts: %w", err)
+		}
+
+		for _, account := range page.Accounts {
+			if account.Status != types.AccountStatusActive {
+				continue
+			}
+			accounts = append(accounts, Account{
+				ID:   aws.ToString(account.Id),
+				Name: aws.ToString(account.Name),
+			})
+		}
+	}
+
+	if len(accounts) == 0 {
+		return nil, apperr.NotFound("no active accounts found")
+	}
+
+	return accounts, nil
+}
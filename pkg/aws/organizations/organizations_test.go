@@ -0,0 +1,31 @@
+package organizations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+type mockOrganizationsClient struct{}
+
+func (m *mockOrganizationsClient) ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error) {
+	return &organizations.ListAccountsOutput{
+		Accounts: []types.Account{
+			{Id: aws.String("111111111111"), Name: aws.String("prod"), Status: types.AccountStatusActive},
+			{Id: aws.String("222222222222"), Name: aws.String("closed"), Status: types.AccountStatusSuspended},
+		},
+	}, nil
+}
+
+func TestListAccounts(t *testing.T) {
+	accounts, err := ListAccounts(context.Background(), &mockOrganizationsClient{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].ID != "111111111111" || accounts[0].Name != "prod" {
+		t.Errorf("expected only the active account, got %v", accounts)
+	}
+}
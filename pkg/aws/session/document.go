@@ -0,0 +1,73 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+type DocumentClient interface {
+	ListDocuments(ctx context.Context, params *ssm.ListDocumentsInput, optFns ...func(*ssm.Options)) (*ssm.ListDocumentsOutput, error)
+}
+
+// ListSessionDocuments returns the names of the Session documents available to the account, sorted by name.
+func ListSessionDocuments(ctx context.Context, client DocumentClient) ([]string, error) {
+	input := &ssm.ListDocumentsInput{
+		Filters: []types.DocumentKeyValuesFilter{{Key: aws.String("DocumentType"), Values: []string{string(types.DocumentTypeSession)}}},
+	}
+
+	var documents []string
+	paginator := ssm.NewListDocumentsPaginator(client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
+		for _, document := range output.DocumentIdentifiers {
+			documents = append(documents, aws.ToString(document.Name))
+		}
+	}
+
+	sort.Strings(documents)
+	return documents, nil
+}
+
+// ParseParameters parses key=value pairs into session document parameters. A key given more than once
+// gets all of its values.
+func ParseParameters(pairs []string) (map[string][]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	parameters := make(map[string][]string)
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		parameters[key] = append(parameters[key], value)
+	}
+	return parameters, nil
+}
+
+// FormatParameters returns the parameters as key=value pairs sorted by key, as ParseParameters accepts them.
+func FormatParameters(parameters map[string][]string) []string {
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		for _, value := range parameters[key] {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,65 @@
+package session
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+type mockDocumentClient struct {
+	pages [][]string
+}
+
+func (m *mockDocumentClient) ListDocuments(ctx context.Context, params *ssm.ListDocumentsInput, optFns ...func(*ssm.Options)) (*ssm.ListDocumentsOutput, error) {
+	page := 0
+	if params.NextToken != nil {
+		page = 1
+	}
+	output := &ssm.ListDocumentsOutput{}
+	for _, name := range m.pages[page] {
+		output.DocumentIdentifiers = append(output.DocumentIdentifiers, types.DocumentIdentifier{Name: aws.String(name)})
+	}
+	if page+1 < len(m.pages) {
+		output.NextToken = aws.String("next")
+	}
+	return output, nil
+}
+
+func TestListSessionDocuments(t *testing.T) {
+	client := &mockDocumentClient{pages: [][]string{{"SessionAsAppUser", "AWS-StartSSHSession"}, {"AWS-StartInteractiveCommand"}}}
+	documents, err := ListSessionDocuments(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"AWS-StartInteractiveCommand", "AWS-StartSSHSession", "SessionAsAppUser"}
+	if !reflect.DeepEqual(documents, expected) {
+		t.Errorf("expected %v, got %v", expected, documents)
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	pairs := []string{"portNumber=5432", "command=psql -c 'select 1=1'", "portNumber=5433"}
+	parameters, err := ParseParameters(pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"portNumber": {"5432", "5433"}, "command": {"psql -c 'select 1=1'"}}
+	if !reflect.DeepEqual(parameters, expected) {
+		t.Errorf("expected %v, got %v", expected, parameters)
+	}
+
+	formatted := FormatParameters(parameters)
+	if !reflect.DeepEqual(formatted, []string{"command=psql -c 'select 1=1'", "portNumber=5432", "portNumber=5433"}) {
+		t.Errorf("unexpected formatted parameters %v", formatted)
+	}
+
+	for _, invalid := range []string{"portNumber", "=5432"} {
+		if _, err := ParseParameters([]string{invalid}); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
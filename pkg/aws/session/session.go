@@ -1,27 +1,190 @@
 package session
 
 import (
+	"context"
+	"eclogin/pkg/interrupt"
+	"eclogin/pkg/recording"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
 )
 
-func StartSession(sessionData []byte, inputData []byte, region string) error {
-	cmd := exec.Command(
+// pluginWaitDelay is how long the plugin has to exit after being stopped before it is killed.
+const pluginWaitDelay = 5 * time.Second
+
+type SSMClient interface {
+	DescribeSessions(ctx context.Context, params *ssm.DescribeSessionsInput, optFns ...func(*ssm.Options)) (*ssm.DescribeSessionsOutput, error)
+	TerminateSession(ctx context.Context, params *ssm.TerminateSessionInput, optFns ...func(*ssm.Options)) (*ssm.TerminateSessionOutput, error)
+}
+
+type InstanceInformationClient interface {
+	DescribeInstanceInformation(ctx context.Context, params *ssm.DescribeInstanceInformationInput, optFns ...func(*ssm.Options)) (*ssm.DescribeInstanceInformationOutput, error)
+}
+
+// Session is an active Session Manager session.
+type Session struct {
+	ID        string
+	Target    string
+	Owner     string
+	Status    string
+	StartDate time.Time
+}
+
+// StartSession runs the session manager plugin for a started session. When recorder is not nil,
+// the plugin runs in a pseudo terminal whose input and output are recorded.
+// The plugin is stopped when ctx is cancelled, e.g. on SIGTERM or SIGHUP.
+func StartSession(ctx context.Context, sessionData []byte, inputData []byte, region string, endpoint string, recorder *recording.Recorder) error {
+	cmd := pluginCommand(ctx, sessionData, inputData, region, endpoint)
+	defer interrupt.PassThrough()()
+
+	if recorder != nil {
+		return recording.Run(cmd, recorder)
+	}
+
+	cmd.Stdout = os.Stdout
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// RunSession runs the session manager plugin for a started session with stdin and stdout instead of
+// the terminal, for non-interactive use such as file transfers. stdin may be nil. The input of the
+// plugin is kept open until it exits, since closing it could end the session before the remote
+// command has finished.
+func RunSession(ctx context.Context, sessionData []byte, inputData []byte, region string, endpoint string, stdin io.Reader, stdout io.Writer) error {
+	cmd := pluginCommand(ctx, sessionData, inputData, region, endpoint)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if stdin != nil {
+		go io.Copy(pipe, stdin)
+	}
+	return cmd.Wait()
+}
+
+// pluginCommand returns the session manager plugin command. When ctx is cancelled, the plugin is asked
+// to exit so that it can restore the terminal, and only killed when it does not exit within pluginWaitDelay.
+func pluginCommand(ctx context.Context, sessionData []byte, inputData []byte, region string, endpoint string) *exec.Cmd {
+	cmd := exec.CommandContext(
+		ctx,
 		"session-manager-plugin",
 		string(sessionData),
 		region,
 		"StartSession",
 		"",
 		string(inputData),
-		"https://ssm."+region+".amazonaws.com",
+		endpoint,
 	)
-	signal.Ignore(os.Interrupt)
-	defer signal.Reset(os.Interrupt)
+	cmd.Cancel = func() error {
+		return stopPlugin(cmd.Process)
+	}
+	cmd.WaitDelay = pluginWaitDelay
+	return cmd
+}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
+// ResolveEndpoint returns the SSM endpoint the client sends requests to. It honors custom
+// endpoints, FIPS and dual-stack settings and the partition of the region (e.g. amazonaws.com.cn).
+func ResolveEndpoint(ctx context.Context, client *ssm.Client) (string, error) {
+	options := client.Options()
+	resolver := options.EndpointResolverV2
+	if resolver == nil {
+		resolver = ssm.NewDefaultEndpointResolverV2()
+	}
 
-	return cmd.Run()
+	endpoint, err := resolver.ResolveEndpoint(ctx, ssm.EndpointParameters{
+		Region:       aws.String(options.Region),
+		UseFIPS:      aws.Bool(options.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled),
+		UseDualStack: aws.Bool(options.EndpointOptions.UseDualStackEndpoint == aws.DualStackEndpointStateEnabled),
+		Endpoint:     options.BaseEndpoint,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve SSM endpoint: %w", err)
+	}
+
+	return endpoint.URI.String(), nil
+}
+
+// ListActiveSessions returns the active sessions, newest first. When owner is not empty,
+// only the sessions started by that IAM identity (user or assumed role ARN) are returned.
+func ListActiveSessions(ctx context.Context, client SSMClient, owner string) ([]Session, error) {
+	input := &ssm.DescribeSessionsInput{State: types.SessionStateActive}
+	if owner != "" {
+		input.Filters = []types.SessionFilter{{Key: types.SessionFilterKeyOwner, Value: aws.String(owner)}}
+	}
+
+	var sessions []Session
+	paginator := ssm.NewDescribeSessionsPaginator(client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe sessions: %w", err)
+		}
+		for _, s := range output.Sessions {
+			sessions = append(sessions, Session{
+				ID:        aws.ToString(s.SessionId),
+				Target:    aws.ToString(s.Target),
+				Owner:     aws.ToString(s.Owner),
+				Status:    string(s.Status),
+				StartDate: aws.ToTime(s.StartDate),
+			})
+		}
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].StartDate.After(sessions[j].StartDate)
+	})
+	return sessions, nil
+}
+
+// TerminateSession ends a session and closes its connection to the target.
+func TerminateSession(ctx context.Context, client SSMClient, sessionID string) error {
+	if _, err := client.TerminateSession(ctx, &ssm.TerminateSessionInput{SessionId: aws.String(sessionID)}); err != nil {
+		return fmt.Errorf("failed to terminate session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// WaitForAgent waits until the SSM agent of the instance reports Online, which can take a while
+// after the instance starts running. It polls every interval and gives up after maxWait.
+func WaitForAgent(ctx context.Context, client InstanceInformationClient, instanceID string, interval, maxWait time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+
+	input := &ssm.DescribeInstanceInformationInput{
+		Filters: []types.InstanceInformationStringFilter{{Key: aws.String("InstanceIds"), Values: []string{instanceID}}},
+	}
+	for {
+		output, err := client.DescribeInstanceInformation(ctx, input)
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("failed to describe instance information: %w", err)
+		}
+		if err == nil && len(output.InstanceInformationList) > 0 && output.InstanceInformationList[0].PingStatus == types.PingStatusOnline {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("the SSM agent of %s did not come online within %v", instanceID, maxWait)
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
 }
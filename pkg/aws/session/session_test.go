@@ -1,9 +1,15 @@
 package session
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
 )
 
 var execCommand func(command string, args ...string) *exec.Cmd
@@ -21,7 +27,7 @@ func TestExecCommand(t *testing.T) {
 	inputJson := []byte(`{"input":"test-input"}`)
 	region := "ap-northeast-1"
 
-	StartSession(sessJson, inputJson, region)
+	StartSession(context.Background(), sessJson, inputJson, region, "https://ssm.ap-northeast-1.amazonaws.com", nil)
 }
 
 func TestHelperProcess(*testing.T) {
@@ -30,3 +36,120 @@ func TestHelperProcess(*testing.T) {
 	}
 	os.Exit(0)
 }
+
+func TestResolveEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      aws.Config
+		expected string
+	}{
+		{
+			name:     "commercial region",
+			cfg:      aws.Config{Region: "ap-northeast-1"},
+			expected: "https://ssm.ap-northeast-1.amazonaws.com",
+		},
+		{
+			name:     "china region",
+			cfg:      aws.Config{Region: "cn-north-1"},
+			expected: "https://ssm.cn-north-1.amazonaws.com.cn",
+		},
+		{
+			name:     "custom endpoint",
+			cfg:      aws.Config{Region: "us-east-1", BaseEndpoint: aws.String("http://localhost:4566")},
+			expected: "http://localhost:4566",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := ResolveEndpoint(context.Background(), ssm.NewFromConfig(tt.cfg))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if endpoint != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, endpoint)
+			}
+		})
+	}
+}
+
+type mockSSMClient struct {
+	describeInput *ssm.DescribeSessionsInput
+	terminated    []string
+}
+
+func (m *mockSSMClient) DescribeSessions(ctx context.Context, params *ssm.DescribeSessionsInput, optFns ...func(*ssm.Options)) (*ssm.DescribeSessionsOutput, error) {
+	m.describeInput = params
+	return &ssm.DescribeSessionsOutput{
+		Sessions: []types.Session{
+			{SessionId: aws.String("alice-old"), Target: aws.String("i-0123456789abcdef0"), Status: types.SessionStatusConnected, StartDate: aws.Time(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))},
+			{SessionId: aws.String("alice-new"), Target: aws.String("ecs:main_task_runtime"), Status: types.SessionStatusConnected, StartDate: aws.Time(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC))},
+		},
+	}, nil
+}
+
+func (m *mockSSMClient) TerminateSession(ctx context.Context, params *ssm.TerminateSessionInput, optFns ...func(*ssm.Options)) (*ssm.TerminateSessionOutput, error) {
+	m.terminated = append(m.terminated, aws.ToString(params.SessionId))
+	return &ssm.TerminateSessionOutput{SessionId: params.SessionId}, nil
+}
+
+func TestListActiveSessions(t *testing.T) {
+	client := &mockSSMClient{}
+	owner := "arn:aws:sts::123456789012:assumed-role/dev/alice"
+	sessions, err := ListActiveSessions(context.Background(), client, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.describeInput.State != types.SessionStateActive {
+		t.Errorf("expected active sessions to be requested, got %s", client.describeInput.State)
+	}
+	filters := client.describeInput.Filters
+	if len(filters) != 1 || filters[0].Key != types.SessionFilterKeyOwner || aws.ToString(filters[0].Value) != owner {
+		t.Errorf("expected an owner filter, got %+v", filters)
+	}
+	if len(sessions) != 2 || sessions[0].ID != "alice-new" || sessions[1].Target != "i-0123456789abcdef0" {
+		t.Errorf("expected sessions newest first, got %+v", sessions)
+	}
+}
+
+func TestTerminateSession(t *testing.T) {
+	client := &mockSSMClient{}
+	if err := TerminateSession(context.Background(), client, "alice-new"); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.terminated) != 1 || client.terminated[0] != "alice-new" {
+		t.Errorf("expected alice-new to be terminated, got %v", client.terminated)
+	}
+}
+
+type mockInstanceInformationClient struct {
+	calls int
+}
+
+func (m *mockInstanceInformationClient) DescribeInstanceInformation(ctx context.Context, params *ssm.DescribeInstanceInformationInput, optFns ...func(*ssm.Options)) (*ssm.DescribeInstanceInformationOutput, error) {
+	m.calls++
+	if m.calls < 2 {
+		return &ssm.DescribeInstanceInformationOutput{}, nil
+	}
+	return &ssm.DescribeInstanceInformationOutput{
+		InstanceInformationList: []types.InstanceInformation{{InstanceId: aws.String("i-123"), PingStatus: types.PingStatusOnline}},
+	}, nil
+}
+
+func TestWaitForAgent(t *testing.T) {
+	client := &mockInstanceInformationClient{}
+	if err := WaitForAgent(context.Background(), client, "i-123", time.Millisecond, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if client.calls != 2 {
+		t.Errorf("expected to poll until the agent is online, got %d calls", client.calls)
+	}
+}
+
+func TestWaitForAgentTimeout(t *testing.T) {
+	client := &mockInstanceInformationClient{calls: -100}
+	if err := WaitForAgent(context.Background(), client, "i-123", time.Millisecond, 20*time.Millisecond); err == nil {
+		t.Error("expected an error when the agent does not come online")
+	}
+}
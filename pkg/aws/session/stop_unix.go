@@ -0,0 +1,13 @@
+//go:build !windows
+
+package session
+
+import (
+	"os"
+	"syscall"
+)
+
+// stopPlugin asks the plugin to exit with SIGTERM, which lets it restore the terminal it put into raw mode.
+func stopPlugin(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package session
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestStopPlugin(t *testing.T) {
+	// The trap stands in for the plugin restoring the terminal before it exits.
+	cmd := exec.Command("sh", "-c", "trap 'exit 7' TERM; while :; do sleep 0.1; done")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if err := stopPlugin(cmd.Process); err != nil {
+		t.Fatal(err)
+	}
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); !errors.As(err, &exitErr) || exitErr.ExitCode() != 7 {
+		t.Errorf("expected the plugin to handle SIGTERM, got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+package session
+
+import "os"
+
+// stopPlugin kills the plugin, since Windows cannot send it a termination signal.
+func stopPlugin(process *os.Process) error {
+	return process.Kill()
+}
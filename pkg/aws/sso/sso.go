@@ -0,0 +1,340 @@
+package sso
+
+import (
+	"context"
+	"eclogin/pkg/apperr"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+const (
+	clientName       = "eclogin"
+	deviceGrantType  = "urn:ietf:params:oauth:grant-type:device_code"
+	refreshGrantType = "refresh_token"
+	accountScope     = "sso:account:access"
+)
+
+type OIDCClient interface {
+	RegisterClient(ctx context.Context, params *ssooidc.RegisterClientInput, optFns ...func(*ssooidc.Options)) (*ssooidc.RegisterClientOutput, error)
+	StartDeviceAuthorization(ctx context.Context, params *ssooidc.StartDeviceAuthorizationInput, optFns ...func(*ssooidc.Options)) (*ssooidc.StartDeviceAuthorizationOutput, error)
+	CreateToken(ctx context.Context, params *ssooidc.CreateTokenInput, optFns ...func(*ssooidc.Options)) (*ssooidc.CreateTokenOutput, error)
+}
+
+// Session is the SSO configuration of a profile, either from an sso-session section or the legacy sso_* keys.
+type Session struct {
+	Name     string
+	Region   string
+	StartURL string
+}
+
+// cachedToken has the same layout as the token cache written by `aws sso login`.
+type cachedToken struct {
+	AccessToken           string    `json:"accessToken"`
+	ExpiresAt             time.Time `json:"expiresAt"`
+	RefreshToken          string    `json:"refreshToken,omitempty"`
+	ClientID              string    `json:"clientId,omitempty"`
+	ClientSecret          string    `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt time.Time `json:"registrationExpiresAt,omitempty"`
+	Region                string    `json:"region,omitempty"`
+	StartURL              string    `json:"startUrl,omitempty"`
+}
+
+var loginMu sync.Mutex
+
+// LoadSession returns the SSO session of the profile, or nil if the profile does not use SSO.
+func LoadSession(ctx context.Context, profile string) (*Session, error) {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	sharedConfig, err := config.LoadSharedConfigProfile(ctx, profile)
+	if err != nil {
+		var notExist config.SharedConfigProfileNotExistError
+		if errors.As(err, &notExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if sharedConfig.SSOSession != nil {
+		return &Session{
+			Name:     sharedConfig.SSOSession.Name,
+			Region:   sharedConfig.SSOSession.SSORegion,
+			StartURL: sharedConfig.SSOSession.SSOStartURL,
+		}, nil
+	}
+	if sharedConfig.SSOStartURL != "" {
+		return &Session{Region: sharedConfig.SSORegion, StartURL: sharedConfig.SSOStartURL}, nil
+	}
+	return nil, nil
+}
+
+// CachePath returns the token cache file used by the SDK for the session.
+func (s *Session) CachePath() (string, error) {
+	if s.Name != "" {
+		return ssocreds.StandardCachedTokenFilepath(s.Name)
+	}
+	return ssocreds.StandardCachedTokenFilepath(s.StartURL)
+}
+
+// TokenValid reports whether the cached token exists and has not expired.
+func (s *Session) TokenValid(now time.Time) bool {
+	path, err := s.CachePath()
+	if err != nil {
+		return false
+	}
+	token, err := readToken(path)
+	if err != nil {
+		return false
+	}
+	return token.AccessToken != "" && now.Before(token.ExpiresAt)
+}
+
+// EnsureLogin renews the cached token when the profile uses SSO and the token is missing or expired.
+// It is a no-op for other profiles. The OIDC client is built from cfg, so that it uses the same
+// endpoint and HTTP client as the other clients.
+func EnsureLogin(ctx context.Context, cfg aws.Config, profile string) error {
+	loginMu.Lock()
+	defer loginMu.Unlock()
+
+	session, err := LoadSession(ctx, profile)
+	if err != nil || session == nil {
+		return err
+	}
+	if session.TokenValid(time.Now()) {
+		return nil
+	}
+	return Renew(ctx, newOIDCClient(cfg, session), session)
+}
+
+func newOIDCClient(cfg aws.Config, session *Session) *ssooidc.Client {
+	return ssooidc.NewFromConfig(cfg, func(o *ssooidc.Options) {
+		o.Region = session.Region
+	})
+}
+
+// Renew gets a new token with the refresh token of the cached one when it has one, without opening
+// the browser, and falls back to Login when there is none or it is rejected.
+func Renew(ctx context.Context, client OIDCClient, session *Session) error {
+	path, err := session.CachePath()
+	if err != nil {
+		return err
+	}
+	if token, err := readToken(path); err == nil && token.refreshable(time.Now()) {
+		if err := refresh(ctx, client, path, token); err == nil {
+			return nil
+		}
+	}
+	return Login(ctx, client, session)
+}
+
+func (t cachedToken) refreshable(now time.Time) bool {
+	return t.RefreshToken != "" && t.ClientID != "" && t.ClientSecret != "" && now.Before(t.RegistrationExpiresAt)
+}
+
+func refresh(ctx context.Context, client OIDCClient, path string, token cachedToken) error {
+	output, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     aws.String(token.ClientID),
+		ClientSecret: aws.String(token.ClientSecret),
+		RefreshToken: aws.String(token.RefreshToken),
+		GrantType:    aws.String(refreshGrantType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refresh SSO token: %w", err)
+	}
+
+	token.AccessToken = aws.ToString(output.AccessToken)
+	token.ExpiresAt = time.Now().UTC().Add(time.Duration(output.ExpiresIn) * time.Second)
+	if output.RefreshToken != nil {
+		token.RefreshToken = aws.ToString(output.RefreshToken)
+	}
+	return writeToken(path, token)
+}
+
+// loginRetryProvider retries the retrieval of credentials once after renewing the SSO token
+// when the token expires or is revoked after EnsureLogin checked it.
+type loginRetryProvider struct {
+	cfg      aws.Config
+	profile  string
+	provider aws.CredentialsProvider
+}
+
+// NewLoginRetryProvider wraps provider, the credentials of a profile loaded into cfg, so that requests
+// rejected because of the SSO token are retried after renewing it.
+func NewLoginRetryProvider(cfg aws.Config, profile string, provider aws.CredentialsProvider) aws.CredentialsProvider {
+	return &loginRetryProvider{cfg: cfg, profile: profile, provider: provider}
+}
+
+func (p *loginRetryProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	credentials, err := p.provider.Retrieve(ctx)
+	if err == nil {
+		return credentials, nil
+	}
+
+	session, loadErr := LoadSession(ctx, p.profile)
+	var unauthorized *ssotypes.UnauthorizedException
+	if loadErr != nil || session == nil || (!errors.As(err, &unauthorized) && session.TokenValid(time.Now())) {
+		return credentials, err
+	}
+
+	loginMu.Lock()
+	renewErr := Renew(ctx, newOIDCClient(p.cfg, session), session)
+	loginMu.Unlock()
+	if renewErr != nil {
+		return credentials, fmt.Errorf("%w (renewing the SSO token failed: %v)", err, renewErr)
+	}
+	return p.provider.Retrieve(ctx)
+}
+
+// Login registers a client, starts device authorization, waits for the user to approve
+// it in the browser and writes the resulting token to the cache file.
+func Login(ctx context.Context, client OIDCClient, session *Session) error {
+	registerInput := &ssooidc.RegisterClientInput{
+		ClientName: aws.String(clientName),
+		ClientType: aws.String("public"),
+	}
+	if session.Name != "" {
+		registerInput.Scopes = []string{accountScope}
+	}
+	registration, err := client.RegisterClient(ctx, registerInput)
+	if err != nil {
+		return fmt.Errorf("failed to register SSO client: %w", err)
+	}
+
+	authorization, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     registration.ClientId,
+		ClientSecret: registration.ClientSecret,
+		StartUrl:     aws.String(session.StartURL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start device authorization: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, `SSO token for %s is expired. Opening the following URL in your browser:
+%s
+
+If the browser does not open, visit %s and enter the code: %s
+
+`,
+		session.StartURL, aws.ToString(authorization.VerificationUriComplete),
+		aws.ToString(authorization.VerificationUri), aws.ToString(authorization.UserCode))
+	openBrowser(aws.ToString(authorization.VerificationUriComplete))
+
+	output, err := pollToken(ctx, client, registration, authorization)
+	if err != nil {
+		return err
+	}
+
+	path, err := session.CachePath()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	return writeToken(path, cachedToken{
+		AccessToken:           aws.ToString(output.AccessToken),
+		ExpiresAt:             now.Add(time.Duration(output.ExpiresIn) * time.Second),
+		RefreshToken:          aws.ToString(output.RefreshToken),
+		ClientID:              aws.ToString(registration.ClientId),
+		ClientSecret:          aws.ToString(registration.ClientSecret),
+		RegistrationExpiresAt: time.Unix(registration.ClientSecretExpiresAt, 0).UTC(),
+		Region:                session.Region,
+		StartURL:              session.StartURL,
+	})
+}
+
+func pollToken(ctx context.Context, client OIDCClient, registration *ssooidc.RegisterClientOutput, authorization *ssooidc.StartDeviceAuthorizationOutput) (*ssooidc.CreateTokenOutput, error) {
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(authorization.ExpiresIn) * time.Second)
+
+	for {
+		output, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     registration.ClientId,
+			ClientSecret: registration.ClientSecret,
+			DeviceCode:   authorization.DeviceCode,
+			GrantType:    aws.String(deviceGrantType),
+		})
+		if err == nil {
+			return output, nil
+		}
+
+		var pending *types.AuthorizationPendingException
+		var slowDown *types.SlowDownException
+		switch {
+		case errors.As(err, &pending):
+		case errors.As(err, &slowDown):
+			interval += 5 * time.Second
+		default:
+			return nil, fmt.Errorf("failed to create SSO token: %w", err)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, apperr.New(apperr.KindAuthentication, "device authorization expired before it was approved")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func readToken(path string) (cachedToken, error) {
+	var token cachedToken
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return token, err
+	}
+	err = json.Unmarshal(data, &token)
+	return token, err
+}
+
+func writeToken(path string, token cachedToken) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create SSO cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SSO token: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write SSO token cache: %w", err)
+	}
+	return nil
+}
+
+var openBrowser = func(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	_ = cmd.Start()
+}
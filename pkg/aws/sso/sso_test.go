@@ -0,0 +1,106 @@
+package sso
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+type mockOIDCClient struct {
+	pending    int
+	registered int
+	grantTypes []string
+}
+
+func (m *mockOIDCClient) RegisterClient(ctx context.Context, params *ssooidc.RegisterClientInput, optFns ...func(*ssooidc.Options)) (*ssooidc.RegisterClientOutput, error) {
+	m.registered++
+	return &ssooidc.RegisterClientOutput{
+		ClientId:              aws.String("client-id"),
+		ClientSecret:          aws.String("client-secret"),
+		ClientSecretExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}, nil
+}
+
+func (m *mockOIDCClient) StartDeviceAuthorization(ctx context.Context, params *ssooidc.StartDeviceAuthorizationInput, optFns ...func(*ssooidc.Options)) (*ssooidc.StartDeviceAuthorizationOutput, error) {
+	return &ssooidc.StartDeviceAuthorizationOutput{
+		DeviceCode: aws.String("device-code"),
+		UserCode:   aws.String("ABCD-EFGH"),
+		ExpiresIn:  600,
+		Interval:   1,
+	}, nil
+}
+
+func (m *mockOIDCClient) CreateToken(ctx context.Context, params *ssooidc.CreateTokenInput, optFns ...func(*ssooidc.Options)) (*ssooidc.CreateTokenOutput, error) {
+	m.grantTypes = append(m.grantTypes, aws.ToString(params.GrantType))
+	if m.pending > 0 {
+		m.pending--
+		return nil, &types.AuthorizationPendingException{}
+	}
+	return &ssooidc.CreateTokenOutput{
+		AccessToken: aws.String("access-token"),
+		ExpiresIn:   3600,
+	}, nil
+}
+
+func TestLogin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	openBrowser = func(string) {}
+
+	session := &Session{Name: "test", Region: "ap-northeast-1", StartURL: "https://example.awsapps.com/start"}
+	if session.TokenValid(time.Now()) {
+		t.Fatal("expected no valid token before login")
+	}
+
+	if err := Login(context.Background(), &mockOIDCClient{pending: 1}, session); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !session.TokenValid(time.Now()) {
+		t.Error("expected valid token after login")
+	}
+	if session.TokenValid(time.Now().Add(2 * time.Hour)) {
+		t.Error("expected token to expire")
+	}
+}
+
+func TestRenewWithRefreshToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	openBrowser = func(string) { t.Error("expected no browser login") }
+
+	session := &Session{Name: "test", Region: "ap-northeast-1", StartURL: "https://example.awsapps.com/start"}
+	path, err := session.CachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := cachedToken{
+		AccessToken:           "expired",
+		ExpiresAt:             time.Now().Add(-time.Hour),
+		RefreshToken:          "refresh-token",
+		ClientID:              "client-id",
+		ClientSecret:          "client-secret",
+		RegistrationExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	if err := writeToken(path, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &mockOIDCClient{}
+	if err := Renew(context.Background(), client, session); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.registered != 0 || len(client.grantTypes) != 1 || client.grantTypes[0] != refreshGrantType {
+		t.Errorf("expected a single refresh, got %d registrations and grants %v", client.registered, client.grantTypes)
+	}
+
+	token, err := readToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access-token" || token.RefreshToken != "refresh-token" || !session.TokenValid(time.Now()) {
+		t.Errorf("expected a refreshed token keeping its refresh token, got %+v", token)
+	}
+}
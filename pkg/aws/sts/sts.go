@@ -0,0 +1,32 @@
+package sts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+type STSClient interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+type Identity struct {
+	Account string
+	ARN     string
+	UserID  string
+}
+
+func GetCallerIdentity(ctx context.Context, client STSClient) (Identity, error) {
+	output, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	return Identity{
+		Account: aws.ToString(output.Account),
+		ARN:     aws.ToString(output.Arn),
+		UserID:  aws.ToString(output.UserId),
+	}, nil
+}
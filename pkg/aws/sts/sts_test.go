@@ -0,0 +1,29 @@
+package sts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+type mockSTSClient struct{}
+
+func (m *mockSTSClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:iam::123456789012:user/test"),
+		UserId:  aws.String("AIDTEST"),
+	}, nil
+}
+
+func TestGetCallerIdentity(t *testing.T) {
+	identity, err := GetCallerIdentity(context.Background(), &mockSTSClient{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Account != "123456789012" || identity.ARN != "arn:aws:iam::123456789012:user/test" {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache stores JSON encoded values on disk, one file per key.
+type Cache struct {
+	dir string
+}
+
+type entry struct {
+	StoredAt time.Time       `json:"stored_at"`
+	Value    json.RawMessage `json:"value"`
+}
+
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the default cache directory of eclogin.
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "eclogin"), nil
+}
+
+// Get decodes the value stored for key into v. It reports false when there is
+// no value or it is older than ttl.
+func (c *Cache) Get(key string, ttl time.Duration, v any) bool {
+	e, err := c.read(key)
+	if err != nil || time.Since(e.StoredAt) > ttl {
+		return false
+	}
+	return json.Unmarshal(e.Value, v) == nil
+}
+
+func (c *Cache) Set(key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache value: %w", err)
+	}
+
+	data, err := json.Marshal(entry{StoredAt: time.Now(), Value: value})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so that concurrent readers never see a partial entry.
+	tmp, err := os.CreateTemp(c.dir, "entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *Cache) read(key string) (entry, error) {
+	var e entry
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return e, err
+	}
+	err = json.Unmarshal(data, &e)
+	return e, err
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	c := New(t.TempDir())
+
+	var clusters []string
+	if c.Get("clusters", time.Minute, &clusters) {
+		t.Error("expected miss for empty cache")
+	}
+
+	if err := c.Set("clusters", []string{"main", "batch"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.Get("clusters", time.Minute, &clusters) {
+		t.Fatal("expected hit")
+	}
+	if !reflect.DeepEqual(clusters, []string{"main", "batch"}) {
+		t.Errorf("unexpected value: %v", clusters)
+	}
+
+	if c.Get("clusters", 0, &clusters) {
+		t.Error("expected expired entry to miss")
+	}
+}
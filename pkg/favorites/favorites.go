@@ -0,0 +1,153 @@
+package favorites
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxRecent is the number of recently used options kept per picker.
+const maxRecent = 10
+
+// Store keeps favorite and recently used picker options per account/region scope.
+// Options are grouped by the picker label, e.g. "Select ECS Service".
+type Store struct {
+	path  string
+	scope string
+	mu    sync.Mutex
+	data  map[string]*scopeData
+}
+
+type scopeData struct {
+	Favorites map[string][]string `json:"favorites,omitempty"`
+	Recent    map[string][]string `json:"recent,omitempty"`
+}
+
+func Open(path string) (*Store, error) {
+	store := &Store{path: path, data: map[string]*scopeData{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read favorites: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store.data); err != nil {
+		return nil, fmt.Errorf("failed to parse favorites: %w", err)
+	}
+	return store, nil
+}
+
+// SetScope selects the account and region that following calls apply to.
+func (s *Store) SetScope(account, region string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scope = account + "/" + region
+}
+
+// Rank returns options with favorites first, then recently used ones, then the rest in their original order.
+func (s *Store) Rank(label string, options []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	available := make(map[string]bool, len(options))
+	for _, option := range options {
+		available[option] = true
+	}
+
+	scope := s.current()
+	ranked := make([]string, 0, len(options))
+	seen := make(map[string]bool, len(options))
+	for _, list := range [][]string{scope.Favorites[label], scope.Recent[label], options} {
+		for _, option := range list {
+			if available[option] && !seen[option] {
+				ranked = append(ranked, option)
+				seen[option] = true
+			}
+		}
+	}
+	return ranked
+}
+
+func (s *Store) IsFavorite(label, option string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return indexOf(s.current().Favorites[label], option) >= 0
+}
+
+func (s *Store) ToggleFavorite(label, option string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scope := s.current()
+	if scope.Favorites == nil {
+		scope.Favorites = map[string][]string{}
+	}
+
+	favorites := scope.Favorites[label]
+	if i := indexOf(favorites, option); i >= 0 {
+		scope.Favorites[label] = append(favorites[:i:i], favorites[i+1:]...)
+	} else {
+		scope.Favorites[label] = append(favorites, option)
+	}
+	return s.save()
+}
+
+// Used moves option to the top of the recently used options.
+func (s *Store) Used(label, option string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scope := s.current()
+	if scope.Recent == nil {
+		scope.Recent = map[string][]string{}
+	}
+
+	recent := []string{option}
+	for _, used := range scope.Recent[label] {
+		if used != option && len(recent) < maxRecent {
+			recent = append(recent, used)
+		}
+	}
+	scope.Recent[label] = recent
+	return s.save()
+}
+
+func (s *Store) current() *scopeData {
+	scope, ok := s.data[s.scope]
+	if !ok {
+		scope = &scopeData{}
+		s.data[s.scope] = scope
+	}
+	return scope
+}
+
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create favorites directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal favorites: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write favorites: %w", err)
+	}
+	return nil
+}
+
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,51 @@
+package favorites
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "favorites.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetScope("123456789012", "ap-northeast-1")
+
+	options := []string{"api", "batch", "web", "worker"}
+	if err := store.Used("Select ECS Service", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ToggleFavorite("Select ECS Service", "worker"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"worker", "web", "api", "batch"}
+	if ranked := store.Rank("Select ECS Service", options); !reflect.DeepEqual(ranked, expected) {
+		t.Errorf("expected %v, got %v", expected, ranked)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.SetScope("123456789012", "ap-northeast-1")
+	if !reopened.IsFavorite("Select ECS Service", "worker") {
+		t.Error("expected favorite to be persisted")
+	}
+
+	reopened.SetScope("123456789012", "us-east-1")
+	if reopened.IsFavorite("Select ECS Service", "worker") {
+		t.Error("expected favorites to be scoped per region")
+	}
+
+	reopened.SetScope("123456789012", "ap-northeast-1")
+	if err := reopened.ToggleFavorite("Select ECS Service", "worker"); err != nil {
+		t.Fatal(err)
+	}
+	if reopened.IsFavorite("Select ECS Service", "worker") {
+		t.Error("expected favorite to be removed")
+	}
+}
@@ -0,0 +1,134 @@
+package history
+
+import (
+	"bufio"
+	"eclogin/pkg/apperr"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxEntries is the number of connections kept in the history file.
+const maxEntries = 1000
+
+// Entry is a successful connection.
+type Entry struct {
+	Type       string    `json:"type"`
+	Account    string    `json:"account,omitempty"`
+	Region     string    `json:"region"`
+	Profile    string    `json:"profile,omitempty"`
+	InstanceID string    `json:"instance_id,omitempty"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Service    string    `json:"service,omitempty"`
+	TaskID     string    `json:"task_id,omitempty"`
+	Container  string    `json:"container,omitempty"`
+	Shell      string    `json:"shell,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+
+	// Document and Parameters are the session document of an EC2 connection, when it is not the default one.
+	Document   string              `json:"document,omitempty"`
+	Parameters map[string][]string `json:"parameters,omitempty"`
+
+	// RoleName is the role assumed in Account by an --all-accounts connection, which is reopened by assuming it again.
+	RoleName string `json:"role_name,omitempty"`
+}
+
+// Target returns a short description of what the entry connected to.
+func (e Entry) Target() string {
+	if e.InstanceID != "" {
+		return e.InstanceID
+	}
+	if e.Service != "" {
+		return fmt.Sprintf("%s/%s/%s", e.Cluster, e.Service, e.Container)
+	}
+	return fmt.Sprintf("%s/%s/%s", e.Cluster, e.TaskID, e.Container)
+}
+
+// Matches reports whether any field of the entry contains query (case-insensitive).
+func (e Entry) Matches(query string) bool {
+	query = strings.ToLower(query)
+	for _, field := range []string{e.Type, e.Account, e.Region, e.Profile, e.InstanceID, e.Cluster, e.Service, e.TaskID, e.Container, e.Document} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store is a history file with one JSON entry per line, oldest first.
+type Store struct {
+	path string
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) Load() ([]Entry, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Append adds the entry and drops the oldest entries beyond maxEntries.
+func (s *Store) Append(entry Entry) error {
+	entries, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	entries = append(entries, entry)
+	if len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// Last returns the most recent entry.
+func (s *Store) Last() (Entry, error) {
+	entries, err := s.Load()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, apperr.NotFound("no connection history")
+	}
+	return entries[len(entries)-1], nil
+}
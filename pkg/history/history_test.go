@@ -0,0 +1,52 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "history.jsonl"))
+
+	if _, err := store.Last(); err == nil {
+		t.Error("expected error for empty history")
+	}
+
+	first := Entry{Type: "ec2", Region: "ap-northeast-1", InstanceID: "i-123", Timestamp: time.Now()}
+	second := Entry{Type: "ecs", Account: "123456789012", RoleName: "OrganizationAccountAccessRole", Region: "ap-northeast-1", Cluster: "main", Service: "api", TaskID: "abc", Container: "app", Timestamp: time.Now()}
+	for _, entry := range []Entry{first, second} {
+		if err := store.Append(entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err := store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	last, err := store.Last()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last.Target() != "main/api/app" {
+		t.Errorf("expected main/api/app, got %s", last.Target())
+	}
+	if last.RoleName != "OrganizationAccountAccessRole" || last.Account != "123456789012" {
+		t.Errorf("expected the assumed role to be kept, got %+v", last)
+	}
+}
+
+func TestEntryMatches(t *testing.T) {
+	entry := Entry{Type: "ecs", Cluster: "Main", Service: "api"}
+	if !entry.Matches("main") {
+		t.Error("expected entry to match main")
+	}
+	if entry.Matches("worker") {
+		t.Error("expected entry not to match worker")
+	}
+}
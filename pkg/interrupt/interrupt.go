@@ -0,0 +1,52 @@
+// Package interrupt cancels the context of a command on SIGINT, SIGTERM and SIGHUP. While an interactive
+// session runs, SIGINT is left to the terminal, which delivers Ctrl-C to the session and its remote shell.
+package interrupt
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// passing counts the sessions that Ctrl-C is passed to.
+var passing atomic.Int32
+
+// NotifyContext returns a context cancelled on SIGINT, SIGTERM or SIGHUP, except for SIGINT
+// while PassThrough is in effect. stop releases the signals.
+func NotifyContext(parent context.Context) (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == os.Interrupt && passing.Load() > 0 {
+					continue
+				}
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
+
+// PassThrough keeps SIGINT from cancelling the context until restore is called, so that Ctrl-C reaches
+// a session running in the foreground instead of ending it. SIGTERM and SIGHUP still cancel the context.
+func PassThrough() (restore func()) {
+	passing.Add(1)
+	return func() {
+		passing.Add(-1)
+	}
+}
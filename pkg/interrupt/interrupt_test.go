@@ -0,0 +1,35 @@
+//go:build !windows
+
+package interrupt
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPassThrough(t *testing.T) {
+	ctx, stop := NotifyContext(context.Background())
+	defer stop()
+
+	restore := PassThrough()
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("expected SIGINT to be passed to the session")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	restore()
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected SIGINT to cancel the context after the session")
+	}
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"eclogin/pkg/apperr"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatCSV   = "csv"
+)
+
+var Formats = []string{FormatTable, FormatJSON, FormatYAML, FormatCSV}
+
+// Write writes records, a slice of structs, in the given format. Table and CSV
+// columns follow the struct fields, named after their json tags.
+func Write[T any](w io.Writer, format string, records []T) error {
+	if records == nil {
+		records = []T{}
+	}
+
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(records); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns[T]()); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := writer.Write(values(record)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatTable:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		header := columns[T]()
+		for i := range header {
+			header[i] = strings.ToUpper(header[i])
+		}
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+		for _, record := range records {
+			fmt.Fprintln(writer, strings.Join(values(record), "\t"))
+		}
+		return writer.Flush()
+	default:
+		return apperr.InvalidInput("unknown output format %q (expected one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+func columns[T any]() []string {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i] = fieldName(t.Field(i))
+	}
+	return names
+}
+
+func values(record any) []string {
+	v := reflect.ValueOf(record)
+	fields := make([]string, v.NumField())
+	for i := range fields {
+		fields[i] = fmt.Sprint(v.Field(i).Interface())
+	}
+	return fields
+}
+
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,56 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+type record struct {
+	Cluster string `json:"cluster" yaml:"cluster"`
+	Service string `json:"service" yaml:"service"`
+}
+
+func TestWrite(t *testing.T) {
+	records := []record{{Cluster: "main", Service: "api"}}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   FormatTable,
+			expected: "CLUSTER  SERVICE\nmain     api\n",
+		},
+		{
+			format:   FormatCSV,
+			expected: "cluster,service\nmain,api\n",
+		},
+		{
+			format:   FormatJSON,
+			expected: "[\n  {\n    \"cluster\": \"main\",\n    \"service\": \"api\"\n  }\n]\n",
+		},
+		{
+			format:   FormatYAML,
+			expected: "- cluster: main\n  service: api\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, records); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "xml", []record{}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -1,7 +1,12 @@
 package prompt
 
 import (
+	"eclogin/pkg/apperr"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"strings"
 
 	"github.com/manifoldco/promptui"
@@ -9,65 +14,217 @@ import (
 )
 
 type Prompter interface {
-	Input(label string, defaultValue string) string
-	Select(label string, options []string) string
+	Input(label string, defaultValue string) (string, error)
+	Select(label string, options []string) (string, error)
 }
 
-type UIPrompter struct{}
+// Ranker orders the options of a picker and keeps the favorites toggled from it.
+type Ranker interface {
+	Rank(label string, options []string) []string
+	IsFavorite(label, option string) bool
+	ToggleFavorite(label, option string) error
+	Used(label, option string) error
+}
+
+type UIPrompter struct {
+	ranker Ranker
+}
+
+// MissingInputError is reported when a value is needed but prompting is disabled.
+type MissingInputError struct {
+	Flag       string
+	Label      string
+	Candidates []string
+}
+
+func (e *MissingInputError) Error() string {
+	var b strings.Builder
+	if e.Flag != "" {
+		fmt.Fprintf(&b, "missing required flag --%s", e.Flag)
+	} else {
+		fmt.Fprintf(&b, "missing input for %q", e.Label)
+	}
+	if len(e.Candidates) > 0 {
+		fmt.Fprintf(&b, " (candidates: %s)", strings.Join(e.Candidates, ", "))
+	}
+	b.WriteString("; prompting is disabled by --no-input or a non-interactive stdin")
+	return b.String()
+}
+
+var interactive = true
+
+// SetInteractive enables or disables prompting. When disabled, inputs fall back to their default,
+// selections with a single option pick it, and any other selection fails with a MissingInputError.
+func SetInteractive(enabled bool) {
+	interactive = enabled
+}
 
-func GetFlagOrInput(cmd *cobra.Command, flagName string, promptMsg string, defaultValue string, prompter Prompter) string {
+func Interactive() bool {
+	return interactive
+}
+
+const (
+	// keyToggleFavorite (Ctrl-F) toggles the favorite of the highlighted option in a picker.
+	keyToggleFavorite = 0x06
+	keyEnter          = '\r'
+	favoriteMark      = "★ "
+)
+
+func GetFlagOrInput(cmd *cobra.Command, flagName string, promptMsg string, defaultValue string, prompter Prompter) (string, error) {
 	flagValue, err := cmd.Flags().GetString(flagName)
 	if err != nil {
-		log.Fatalf("Failed to get flag '%s': %v", flagName, err)
+		return "", fmt.Errorf("failed to get flag '%s': %w", flagName, err)
 	}
 
 	if flagValue == "" {
-		flagValue = prompter.Input(promptMsg, defaultValue)
+		if !interactive {
+			return defaultValue, nil
+		}
+		return prompter.Input(promptMsg, defaultValue)
 	}
-	return flagValue
+	return flagValue, nil
 }
 
-func GetFlagOrSelect(cmd *cobra.Command, flagName string, promptMsg string, options []string, prompter Prompter) string {
+func GetFlagOrSelect(cmd *cobra.Command, flagName string, promptMsg string, options []string, prompter Prompter) (string, error) {
 	flagValue, err := cmd.Flags().GetString(flagName)
 	if err != nil {
-		log.Fatalf("Failed to get flag '%s': %v", flagName, err)
+		return "", fmt.Errorf("failed to get flag '%s': %w", flagName, err)
 	}
 	if flagValue != "" {
-		return flagValue
+		return flagValue, nil
+	}
+	if !interactive {
+		return singleOption(&MissingInputError{Flag: flagName, Label: promptMsg, Candidates: options})
 	}
 	return prompter.Select(promptMsg, options)
 }
 
+// singleOption returns the only candidate of a selection that cannot be prompted for.
+func singleOption(missing *MissingInputError) (string, error) {
+	if len(missing.Candidates) == 1 {
+		return missing.Candidates[0], nil
+	}
+	return "", &apperr.Error{Kind: apperr.KindInvalidInput, Err: missing}
+}
+
 func NewUIPrompter() Prompter {
 	return &UIPrompter{}
 }
 
-func (p *UIPrompter) Input(label string, defaultValue string) string {
+// NewRankedPrompter returns a prompter whose pickers list favorites and recently used options first.
+func NewRankedPrompter(ranker Ranker) Prompter {
+	return &UIPrompter{ranker: ranker}
+}
+
+func (p *UIPrompter) Input(label string, defaultValue string) (string, error) {
+	if !interactive {
+		return defaultValue, nil
+	}
 	prompt := promptui.Prompt{
 		Label:   label,
 		Default: defaultValue,
 	}
 	result, err := prompt.Run()
 	if err != nil {
-		log.Fatalf("Failed to get user input: %v\n", err)
+		return "", promptError("failed to get user input", err)
 	}
-	return result
+	return result, nil
 }
 
-func (p *UIPrompter) Select(label string, options []string) string {
+func (p *UIPrompter) Select(label string, options []string) (string, error) {
+	if !interactive {
+		return singleOption(&MissingInputError{Label: label, Candidates: options})
+	}
+	if len(options) == 0 {
+		return "", apperr.NotFound("nothing to select for %q", label)
+	}
+	if p.ranker == nil {
+		return selectOption(label, options, nil)
+	}
+
+	for {
+		ranked := p.ranker.Rank(label, options)
+		items := make([]string, len(ranked))
+		for i, option := range ranked {
+			if p.ranker.IsFavorite(label, option) {
+				items[i] = favoriteMark + option
+			} else {
+				items[i] = option
+			}
+		}
+
+		stdin := &toggleReader{reader: os.Stdin}
+		index, err := selectIndex(fmt.Sprintf("%s (Ctrl-F: toggle favorite)", label), items, stdin)
+		if err != nil {
+			return "", err
+		}
+		if stdin.toggled {
+			if err := p.ranker.ToggleFavorite(label, ranked[index]); err != nil {
+				log.Printf("Failed to save favorite: %v", err)
+			}
+			continue
+		}
+
+		if err := p.ranker.Used(label, ranked[index]); err != nil {
+			log.Printf("Failed to save recently used option: %v", err)
+		}
+		return ranked[index], nil
+	}
+}
+
+func selectOption(label string, options []string, stdin io.ReadCloser) (string, error) {
+	index, err := selectIndex(label, options, stdin)
+	if err != nil {
+		return "", err
+	}
+	return options[index], nil
+}
+
+func selectIndex(label string, options []string, stdin io.ReadCloser) (int, error) {
 	prompt := promptui.Select{
 		Label:             label,
 		Items:             options,
 		StartInSearchMode: true,
+		Stdin:             stdin,
 		Searcher: func(input string, index int) bool {
 			option := options[index]
 			// Filter options by checking if the input is contained in the option (case-insensitive)
 			return strings.Contains(strings.ToLower(option), strings.ToLower(input))
 		},
 	}
-	_, result, err := prompt.Run()
+	index, _, err := prompt.Run()
 	if err != nil {
-		log.Fatalf("Failed to get user selection: %v\n", err)
+		return 0, promptError("failed to get user selection", err)
+	}
+	return index, nil
+}
+
+// promptError reports Ctrl-C and Ctrl-D in a prompt as apperr.ErrCancelled.
+func promptError(message string, err error) error {
+	if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) || errors.Is(err, promptui.ErrAbort) {
+		return apperr.ErrCancelled
 	}
-	return result
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// toggleReader turns the favorite key into Enter, so that the picker returns the
+// highlighted option, and remembers that a toggle was requested.
+type toggleReader struct {
+	reader  io.Reader
+	toggled bool
+}
+
+func (r *toggleReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == keyToggleFavorite {
+			p[i] = keyEnter
+			r.toggled = true
+		}
+	}
+	return n, err
+}
+
+func (r *toggleReader) Close() error {
+	return nil
 }
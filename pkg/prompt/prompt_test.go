@@ -1,8 +1,12 @@
 package prompt
 
 import (
+	"eclogin/pkg/apperr"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
 
@@ -11,12 +15,12 @@ type MockPrompter struct {
 	selectResult string
 }
 
-func (m *MockPrompter) Input(label string, defaultValue string) string {
-	return m.inputResult
+func (m *MockPrompter) Input(label string, defaultValue string) (string, error) {
+	return m.inputResult, nil
 }
 
-func (m *MockPrompter) Select(label string, options []string) string {
-	return m.selectResult
+func (m *MockPrompter) Select(label string, options []string) (string, error) {
+	return m.selectResult, nil
 }
 
 func TestGetFlagOrInput(t *testing.T) {
@@ -27,7 +31,10 @@ func TestGetFlagOrInput(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	result := GetFlagOrInput(cmd, "test-flag", "Enter value:", "default", &MockPrompter{})
+	result, err := GetFlagOrInput(cmd, "test-flag", "Enter value:", "default", &MockPrompter{})
+	if err != nil {
+		t.Fatal(err)
+	}
 	if result != "flag-value" {
 		t.Errorf("Expected flag-value, got %s", result)
 	}
@@ -42,7 +49,10 @@ func TestGetFlagOrSelect(t *testing.T) {
 		t.Fatal(err)
 	}
 	options := []string{"option1", "option2", "option3"}
-	result := GetFlagOrSelect(cmd, "test-flag", "Select option:", options, &MockPrompter{})
+	result, err := GetFlagOrSelect(cmd, "test-flag", "Select option:", options, &MockPrompter{})
+	if err != nil {
+		t.Fatal(err)
+	}
 	if result != "option1" {
 		t.Errorf("Expected option1, got %s", result)
 	}
@@ -50,7 +60,7 @@ func TestGetFlagOrSelect(t *testing.T) {
 
 func TestPromptInput(t *testing.T) {
 	mock := &MockPrompter{inputResult: "test input"}
-	result := mock.Input("Test Label", "default")
+	result, _ := mock.Input("Test Label", "default")
 	if result != "test input" {
 		t.Errorf("Expected 'test input', got %s", result)
 	}
@@ -59,8 +69,67 @@ func TestPromptInput(t *testing.T) {
 func TestPromptSelect(t *testing.T) {
 	mock := &MockPrompter{selectResult: "option1"}
 	options := []string{"option1", "option2", "option3"}
-	result := mock.Select("Test Label", options)
+	result, _ := mock.Select("Test Label", options)
 	if result != "option1" {
 		t.Errorf("Expected 'option1', got %s", result)
 	}
 }
+
+func TestToggleReader(t *testing.T) {
+	reader := &toggleReader{reader: strings.NewReader("ab\x06")}
+	buf := make([]byte, 8)
+	n, err := reader.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "ab\r" {
+		t.Errorf("expected toggle key to be replaced with enter, got %q", buf[:n])
+	}
+	if !reader.toggled {
+		t.Error("expected toggled to be set")
+	}
+}
+
+func TestGetFlagOrSelectNonInteractive(t *testing.T) {
+	SetInteractive(false)
+	defer SetInteractive(true)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("test-flag", "", "test flag")
+
+	result, err := GetFlagOrSelect(cmd, "test-flag", "Select option:", []string{"only"}, &MockPrompter{selectResult: "prompted"})
+	if err != nil || result != "only" {
+		t.Errorf("Expected the single option to be picked, got %s (%v)", result, err)
+	}
+
+	result, err = GetFlagOrInput(cmd, "test-flag", "Enter value:", "default", &MockPrompter{inputResult: "prompted"})
+	if err != nil || result != "default" {
+		t.Errorf("Expected default, got %s (%v)", result, err)
+	}
+
+	_, err = GetFlagOrSelect(cmd, "test-flag", "Select option:", []string{"a", "b"}, &MockPrompter{selectResult: "prompted"})
+	var missing *MissingInputError
+	if !errors.As(err, &missing) || missing.Flag != "test-flag" {
+		t.Errorf("Expected a MissingInputError, got %v", err)
+	}
+	if code := apperr.ExitCode(err); code != apperr.ExitInvalidInput {
+		t.Errorf("Expected exit code %d, got %d", apperr.ExitInvalidInput, code)
+	}
+}
+
+func TestPromptError(t *testing.T) {
+	if err := promptError("failed", promptui.ErrInterrupt); err != apperr.ErrCancelled {
+		t.Errorf("Expected ErrCancelled, got %v", err)
+	}
+	if err := promptError("failed", errors.New("boom")); err.Error() != "failed: boom" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestMissingInputError(t *testing.T) {
+	err := &MissingInputError{Flag: "service", Candidates: []string{"api", "web"}}
+	expected := "missing required flag --service (candidates: api, web); prompting is disabled by --no-input or a non-interactive stdin"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
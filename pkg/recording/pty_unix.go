@@ -0,0 +1,56 @@
+//go:build !windows
+
+package recording
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// Run runs cmd in a pseudo terminal connected to the current terminal and records its input and output.
+// The pseudo terminal follows the size of the current terminal.
+func Run(cmd *exec.Cmd, r *Recorder) error {
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+	go func() {
+		for range resize {
+			_ = pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+	_ = pty.InheritSize(os.Stdin, ptmx)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+	}
+
+	stopStdin, err := CopyStdin(ptmx, r)
+	if err != nil {
+		return err
+	}
+	// Reading the pseudo terminal fails with EIO once the command exits.
+	_, _ = io.Copy(io.MultiWriter(os.Stdout, r.Output()), ptmx)
+	err = cmd.Wait()
+
+	// Closing the pseudo terminal first makes sure the copy of stdin is not blocked writing to it.
+	ptmx.Close()
+	stopStdin()
+	return err
+}
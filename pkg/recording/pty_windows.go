@@ -0,0 +1,11 @@
+package recording
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Run is not supported on Windows, which has no pseudo terminals compatible with the session manager plugin.
+func Run(_ *exec.Cmd, _ *Recorder) error {
+	return errors.New("recording sessions is not supported on Windows")
+}
@@ -0,0 +1,154 @@
+// Package recording records terminal sessions as asciicast v2 files and plays them back.
+// See https://docs.asciinema.org/manual/asciicast/v2/ for the format.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	eventOutput = "o"
+	eventInput  = "i"
+
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// Header is the first line of an asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes the input and output events of a session to an asciicast file.
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	start   time.Time
+	pending map[string][]byte
+}
+
+// Create creates the recording file at path, with the terminal size and title in its header.
+func Create(path string, width, height int, title string) (*Recorder, error) {
+	if width <= 0 || height <= 0 {
+		width, height = defaultWidth, defaultHeight
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	r := &Recorder{
+		file:    file,
+		writer:  bufio.NewWriter(file),
+		start:   time.Now(),
+		pending: map[string][]byte{},
+	}
+	header := Header{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"SHELL": os.Getenv("SHELL"), "TERM": os.Getenv("TERM")},
+	}
+	if err := r.writeLine(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Output returns a writer that records what it is given as output events.
+func (r *Recorder) Output() io.Writer {
+	return eventWriter{recorder: r, kind: eventOutput}
+}
+
+// Input returns a writer that records what it is given as input events.
+func (r *Recorder) Input() io.Writer {
+	return eventWriter{recorder: r, kind: eventInput}
+}
+
+// Close flushes the events and closes the file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writer.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+func (r *Recorder) record(kind string, p []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A multi-byte character split between two writes is kept until it is complete,
+	// since JSON strings cannot hold partial UTF-8 sequences.
+	data := append(r.pending[kind], p...)
+	n := validPrefix(data)
+	r.pending[kind] = append([]byte(nil), data[n:]...)
+	if n == 0 {
+		return nil
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	return r.writeLine([]any{elapsed, kind, string(data[:n])})
+}
+
+func (r *Recorder) writeLine(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := r.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// validPrefix returns the length of data without an incomplete UTF-8 sequence at its end.
+// Invalid bytes elsewhere are left to the JSON encoder.
+func validPrefix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(data[i]) {
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			return i
+		}
+		break
+	}
+	return len(data)
+}
+
+type eventWriter struct {
+	recorder *Recorder
+	kind     string
+}
+
+func (w eventWriter) Write(p []byte) (int, error) {
+	if err := w.recorder.record(w.kind, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
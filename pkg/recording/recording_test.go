@@ -0,0 +1,95 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.cast")
+	recorder, err := Create(path, 120, 40, "i-0123456789abcdef0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.Input().Write([]byte("ls\r"))
+	recorder.Output().Write([]byte("caf\xc3"))
+	recorder.Output().Write([]byte("\xa9\r\n"))
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan()
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 120 || header.Height != 40 || header.Title != "i-0123456789abcdef0" {
+		t.Errorf("unexpected header: %+v", header)
+	}
+
+	var events [][]any
+	for scanner.Scan() {
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+
+	expected := [][2]string{{"i", "ls\r"}, {"o", "caf"}, {"o", "é\r\n"}}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %v", len(expected), events)
+	}
+	for i, event := range events {
+		if event[1] != expected[i][0] || event[2] != expected[i][1] {
+			t.Errorf("event %d: expected %v, got %v", i, expected[i], event)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	cast := strings.Join([]string{
+		`{"version": 2, "width": 80, "height": 24}`,
+		`[0.1, "o", "hello "]`,
+		`[0.2, "i", "ignored"]`,
+		`[30.0, "o", "world"]`,
+	}, "\n")
+
+	var out bytes.Buffer
+	start := time.Now()
+	header, err := Replay(context.Background(), strings.NewReader(cast), &out, 10, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Width != 80 {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	if out.String() != "hello world" {
+		t.Errorf("expected output events only, got %q", out.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected pauses to be shortened, took %v", elapsed)
+	}
+}
+
+func TestReplayInvalidVersion(t *testing.T) {
+	_, err := Replay(context.Background(), strings.NewReader(`{"version": 1}`), &bytes.Buffer{}, 1, 0)
+	if err == nil {
+		t.Error("expected an error for asciicast v1")
+	}
+}
@@ -0,0 +1,64 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Replay writes the output events of the asciicast in r to w with their original timing,
+// divided by speed. Pauses longer than maxIdle are shortened to it unless maxIdle is zero.
+func Replay(ctx context.Context, r io.Reader, w io.Writer, speed float64, maxIdle time.Duration) (Header, error) {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var header Header
+	if !scanner.Scan() {
+		return header, fmt.Errorf("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, fmt.Errorf("invalid recording header: %w", err)
+	}
+	if header.Version != 2 {
+		return header, fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	var previous float64
+	for scanner.Scan() {
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+			return header, fmt.Errorf("invalid recording event: %s", scanner.Text())
+		}
+		elapsed, _ := event[0].(float64)
+		kind, _ := event[1].(string)
+		data, _ := event[2].(string)
+		if kind != eventOutput {
+			continue
+		}
+
+		delay := time.Duration((elapsed - previous) / speed * float64(time.Second))
+		previous = elapsed
+		if maxIdle > 0 && delay > maxIdle {
+			delay = maxIdle
+		}
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+				return header, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		if _, err := io.WriteString(w, data); err != nil {
+			return header, err
+		}
+	}
+	return header, scanner.Err()
+}
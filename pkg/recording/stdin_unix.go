@@ -0,0 +1,91 @@
+//go:build !windows
+
+package recording
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cancelableReader reads a file, such as stdin, until Cancel is called. A plain read of stdin cannot be
+// interrupted, so a goroutine copying it would stay blocked after the session ends and swallow the next
+// keystroke, which belongs to whatever prompts next.
+type cancelableReader struct {
+	fd     int
+	cancel [2]int
+}
+
+func newCancelableReader(file *os.File) (*cancelableReader, error) {
+	r := &cancelableReader{fd: int(file.Fd())}
+	if err := unix.Pipe(r.cancel[:]); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Read waits until the file is readable or Cancel is called, in which case it returns io.EOF.
+func (r *cancelableReader) Read(p []byte) (int, error) {
+	for {
+		var fds unix.FdSet
+		fds.Set(r.fd)
+		fds.Set(r.cancel[0])
+		if _, err := unix.Select(max(r.fd, r.cancel[0])+1, &fds, nil, nil, nil); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return 0, err
+		}
+		if fds.IsSet(r.cancel[0]) {
+			return 0, io.EOF
+		}
+
+		n, err := unix.Read(r.fd, p)
+		if errors.Is(err, unix.EINTR) || errors.Is(err, unix.EAGAIN) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, io.EOF
+		}
+		return n, nil
+	}
+}
+
+// Cancel makes pending and later reads return io.EOF without consuming any input.
+func (r *cancelableReader) Cancel() {
+	_, _ = unix.Write(r.cancel[1], []byte{0})
+}
+
+func (r *cancelableReader) Close() error {
+	return errors.Join(unix.Close(r.cancel[0]), unix.Close(r.cancel[1]))
+}
+
+// CopyStdin copies stdin to w in the background, recording it when recorder is not nil, until stop is called.
+// stop returns once stdin is no longer read, so the recorder can be closed and the next prompt gets the following keystrokes.
+func CopyStdin(w io.Writer, recorder *Recorder) (stop func(), err error) {
+	stdin, err := newCancelableReader(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	var input io.Reader = stdin
+	if recorder != nil {
+		input = io.TeeReader(stdin, recorder.Input())
+	}
+	copied := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(w, input)
+		close(copied)
+	}()
+
+	return func() {
+		stdin.Cancel()
+		<-copied
+		stdin.Close()
+	}, nil
+}
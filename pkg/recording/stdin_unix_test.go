@@ -0,0 +1,108 @@
+//go:build !windows
+
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCancelableReader(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+
+	r, err := newCancelableReader(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	pw.Write([]byte("ls\n"))
+	buf := make([]byte, 16)
+	if n, err := r.Read(buf); err != nil || string(buf[:n]) != "ls\n" {
+		t.Fatalf("expected ls, got %q (%v)", buf[:n], err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Read(buf)
+		done <- err
+	}()
+	r.Cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("expected io.EOF after Cancel, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Cancel to interrupt a pending read")
+	}
+
+	// Input written after the cancellation is left for the next reader.
+	pw.Write([]byte("y"))
+	if n, err := pr.Read(buf); err != nil || string(buf[:n]) != "y" {
+		t.Errorf("expected the next keystroke to be left unread, got %q (%v)", buf[:n], err)
+	}
+}
+
+func TestCopyStdin(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+
+	stdin := os.Stdin
+	os.Stdin = pr
+	defer func() { os.Stdin = stdin }()
+
+	var copied safeBuffer
+	stop, err := CopyStdin(&copied, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw.Write([]byte("exit\n"))
+	for deadline := time.Now().Add(time.Second); copied.String() != "exit\n"; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected exit to be copied, got %q", copied.String())
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected stop to return while stdin is idle")
+	}
+}
+
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
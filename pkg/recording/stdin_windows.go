@@ -0,0 +1,43 @@
+package recording
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// discardingReader reads a file until stopped, after which its input is dropped. A console read cannot be
+// interrupted, so a pending read still takes one more keystroke, but it is neither forwarded nor recorded.
+type discardingReader struct {
+	file    *os.File
+	stopped atomic.Bool
+}
+
+func (r *discardingReader) Read(p []byte) (int, error) {
+	if r.stopped.Load() {
+		return 0, io.EOF
+	}
+	n, err := r.file.Read(p)
+	if r.stopped.Load() {
+		return 0, io.EOF
+	}
+	return n, err
+}
+
+// CopyStdin copies stdin to w in the background, recording it when recorder is not nil, until stop is called.
+// stop does not wait for a pending read of stdin, which cannot be interrupted on Windows.
+func CopyStdin(w io.Writer, recorder *Recorder) (stop func(), err error) {
+	stdin := &discardingReader{file: os.Stdin}
+
+	var input io.Reader = stdin
+	if recorder != nil {
+		input = io.TeeReader(stdin, recorder.Input())
+	}
+	go func() {
+		_, _ = io.Copy(w, input)
+	}()
+
+	return func() {
+		stdin.stopped.Store(true)
+	}, nil
+}
@@ -0,0 +1,138 @@
+package settings
+
+import (
+	"eclogin/pkg/apperr"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	TargetTypeEC2 = "ec2"
+	TargetTypeECS = "ecs"
+	// TargetTypeLocal is only used for local Docker sessions, which cannot be aliased.
+	TargetTypeLocal = "local"
+)
+
+// Settings is the user configuration stored in ~/.config/eclogin/config.yaml.
+// When RecordDir is set, every session is recorded to an asciicast file in it.
+type Settings struct {
+	DefaultRegion  string                   `yaml:"default_region,omitempty"`
+	DefaultProfile string                   `yaml:"default_profile,omitempty"`
+	Shell          string                   `yaml:"shell,omitempty"`
+	Aliases        map[string]Alias         `yaml:"aliases,omitempty"`
+	CacheTTL       map[string]time.Duration `yaml:"cache_ttl,omitempty"`
+	RecordDir      string                   `yaml:"record_dir,omitempty"`
+	Audit          Audit                    `yaml:"audit,omitempty"`
+	Reconnect      Reconnect                `yaml:"reconnect,omitempty"`
+}
+
+// Reconnect configures reconnecting to the same target when a session ends abnormally.
+// Unless Auto is set, the user is asked first. Values that are not set use the defaults.
+type Reconnect struct {
+	Disabled   bool          `yaml:"disabled,omitempty"`
+	Auto       bool          `yaml:"auto,omitempty"`
+	Retries    int           `yaml:"retries,omitempty"`
+	Backoff    time.Duration `yaml:"backoff,omitempty"`
+	MaxBackoff time.Duration `yaml:"max_backoff,omitempty"`
+}
+
+// Audit configures the audit log of sessions. It is kept in Dir()/audit.jsonl unless Path is set.
+type Audit struct {
+	Disabled bool           `yaml:"disabled,omitempty"`
+	Path     string         `yaml:"path,omitempty"`
+	Syslog   *SyslogForward `yaml:"syslog,omitempty"`
+	HTTP     *HTTPForward   `yaml:"http,omitempty"`
+}
+
+// SyslogForward forwards audit events to a syslog server. Network is udp (default) or tcp.
+type SyslogForward struct {
+	Network string `yaml:"network,omitempty"`
+	Address string `yaml:"address"`
+}
+
+// HTTPForward posts audit events to an HTTP endpoint with the given headers.
+type HTTPForward struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// Alias is a named connection target used by `eclogin connect`.
+type Alias struct {
+	Type       string `yaml:"type"`
+	Profile    string `yaml:"profile,omitempty"`
+	Region     string `yaml:"region,omitempty"`
+	InstanceID string `yaml:"instance_id,omitempty"`
+	Name       string `yaml:"name,omitempty"`
+	Cluster    string `yaml:"cluster,omitempty"`
+	Service    string `yaml:"service,omitempty"`
+	Container  string `yaml:"container,omitempty"`
+	Shell      string `yaml:"shell,omitempty"`
+	Command    string `yaml:"command,omitempty"`
+}
+
+// Dir returns the directory eclogin keeps its configuration in.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "eclogin"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "eclogin"), nil
+}
+
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// Load reads the settings file. A missing file yields empty settings.
+func Load() (*Settings, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+func LoadFile(path string) (*Settings, error) {
+	settings := &Settings{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for name, alias := range settings.Aliases {
+		if alias.Type != TargetTypeEC2 && alias.Type != TargetTypeECS {
+			return nil, fmt.Errorf("alias %s: unknown type %q (expected %s or %s)", name, alias.Type, TargetTypeEC2, TargetTypeECS)
+		}
+	}
+
+	return settings, nil
+}
+
+func (s *Settings) Alias(name string) (Alias, error) {
+	alias, ok := s.Aliases[name]
+	if !ok {
+		return Alias{}, apperr.NotFound("alias %s not found", name)
+	}
+	return alias, nil
+}
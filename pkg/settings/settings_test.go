@@ -0,0 +1,81 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `default_region: us-east-1
+default_profile: dev
+shell: /bin/bash
+cache_ttl:
+  services: 5m
+aliases:
+  prod-api:
+    type: ecs
+    profile: prod
+    cluster: main
+    service: api
+    container: app
+  db:
+    type: ec2
+    name: bastion
+    command: psql -h db.internal
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.DefaultRegion != "us-east-1" || settings.DefaultProfile != "dev" || settings.Shell != "/bin/bash" {
+		t.Errorf("unexpected defaults: %+v", settings)
+	}
+
+	if settings.CacheTTL["services"] != 5*time.Minute {
+		t.Errorf("expected services TTL of 5m, got %v", settings.CacheTTL["services"])
+	}
+
+	alias, err := settings.Alias("prod-api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if alias.Type != TargetTypeECS || alias.Cluster != "main" || alias.Service != "api" || alias.Container != "app" {
+		t.Errorf("unexpected alias: %+v", alias)
+	}
+
+	if alias, _ := settings.Alias("db"); alias.Command != "psql -h db.internal" {
+		t.Errorf("unexpected alias command: %q", alias.Command)
+	}
+
+	if _, err := settings.Alias("missing"); err == nil {
+		t.Error("expected error for missing alias")
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	settings, err := LoadFile(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(settings.Aliases) != 0 {
+		t.Errorf("expected empty settings, got %+v", settings)
+	}
+}
+
+func TestLoadFileUnknownType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("aliases:\n  x:\n    type: lambda\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadFile(path); err == nil {
+		t.Error("expected error for unknown alias type")
+	}
+}
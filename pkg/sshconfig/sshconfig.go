@@ -0,0 +1,125 @@
+// Package sshconfig writes Host blocks for instances to an ssh_config file that is included
+// from ~/.ssh/config. Each region and profile is kept in its own section of the file, so that
+// regenerating one does not touch the others.
+package sshconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	beginMarker = "# BEGIN eclogin "
+	endMarker   = "# END eclogin "
+)
+
+var invalidAliasChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Host is a Host block. Empty fields are omitted.
+type Host struct {
+	Alias        string
+	HostName     string
+	User         string
+	IdentityFile string
+	ProxyCommand string
+}
+
+// Alias turns an instance name into a Host alias, replacing whitespace and
+// characters with a special meaning in ssh_config patterns with dashes.
+func Alias(name string) string {
+	return strings.Trim(invalidAliasChars.ReplaceAllString(name, "-"), "-")
+}
+
+// Render returns the Host blocks of hosts.
+func Render(hosts []Host) string {
+	var b strings.Builder
+	for i, host := range hosts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Host %s\n", host.Alias)
+		for _, option := range [][2]string{
+			{"HostName", host.HostName},
+			{"User", host.User},
+			{"IdentityFile", host.IdentityFile},
+			{"ProxyCommand", host.ProxyCommand},
+		} {
+			if option[1] != "" {
+				fmt.Fprintf(&b, "  %s %s\n", option[0], option[1])
+			}
+		}
+	}
+	return b.String()
+}
+
+// UpdateSection replaces the section called name in content with body, or appends it when there is none.
+func UpdateSection(content, name, body string) string {
+	begin := beginMarker + name + "\n"
+	end := endMarker + name + "\n"
+	section := begin + body + end
+
+	start := strings.Index(content, begin)
+	if start < 0 {
+		if content != "" && !strings.HasSuffix(content, "\n\n") {
+			content = strings.TrimRight(content, "\n") + "\n\n"
+		}
+		return content + section
+	}
+
+	stop := strings.Index(content[start:], end)
+	if stop < 0 {
+		return content[:start] + section
+	}
+	return content[:start] + section + content[start+stop+len(end):]
+}
+
+// WriteSection writes hosts to the section called name of the file at path, creating the file when needed.
+func WriteSection(path, name string, hosts []Host) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create ssh config directory: %w", err)
+	}
+
+	content := UpdateSection(string(data), name, Render(hosts))
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Includes reports whether the ssh_config file at configPath has an Include directive naming path.
+// Only the literal path and its ~/ form are recognized.
+func Includes(configPath, path string) bool {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return false
+	}
+
+	candidates := []string{path}
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		candidates = append(candidates, "~/"+filepath.ToSlash(strings.TrimPrefix(path, home+string(filepath.Separator))))
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "Include") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			for _, candidate := range candidates {
+				if field == candidate {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
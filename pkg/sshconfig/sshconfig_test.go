@@ -0,0 +1,90 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	tests := map[string]string{
+		"bastion":        "bastion",
+		"web server #1":  "web-server-1",
+		"api*prod?":      "api-prod",
+		"db.internal_01": "db.internal_01",
+	}
+	for name, expected := range tests {
+		if alias := Alias(name); alias != expected {
+			t.Errorf("Alias(%q): expected %q, got %q", name, expected, alias)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	hosts := []Host{
+		{Alias: "bastion", HostName: "i-0123456789abcdef0", User: "ec2-user", ProxyCommand: "eclogin ec2 proxy %h %p --region ap-northeast-1"},
+		{Alias: "web", HostName: "i-0fedcba9876543210"},
+	}
+
+	expected := `Host bastion
+  HostName i-0123456789abcdef0
+  User ec2-user
+  ProxyCommand eclogin ec2 proxy %h %p --region ap-northeast-1
+
+Host web
+  HostName i-0fedcba9876543210
+`
+	if rendered := Render(hosts); rendered != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, rendered)
+	}
+}
+
+func TestUpdateSection(t *testing.T) {
+	content := UpdateSection("", "dev/ap-northeast-1", "Host a\n")
+	content = UpdateSection(content, "prod/us-east-1", "Host b\n")
+	content = UpdateSection(content, "dev/ap-northeast-1", "Host c\n")
+
+	expected := `# BEGIN eclogin dev/ap-northeast-1
+Host c
+# END eclogin dev/ap-northeast-1
+
+# BEGIN eclogin prod/us-east-1
+Host b
+# END eclogin prod/us-east-1
+`
+	if content != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, content)
+	}
+}
+
+func TestWriteSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eclogin", "ssh_config")
+	if err := WriteSection(path, "ap-northeast-1", []Host{{Alias: "bastion", HostName: "i-0123456789abcdef0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Host bastion\n  HostName i-0123456789abcdef0\n") {
+		t.Errorf("unexpected content:\n%s", data)
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	included := filepath.Join(dir, "eclogin", "ssh_config")
+
+	if err := os.WriteFile(configPath, []byte("include "+included+"\n\nHost *\n  ServerAliveInterval 60\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !Includes(configPath, included) {
+		t.Error("expected the file to be included")
+	}
+	if Includes(configPath, filepath.Join(dir, "other")) {
+		t.Error("expected another file not to be included")
+	}
+}
@@ -0,0 +1,63 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const progressInterval = 100 * time.Millisecond
+
+// Progress counts the bytes written to it and prints the progress of a transfer on a single line.
+type Progress struct {
+	w       io.Writer
+	name    string
+	total   int64
+	done    int64
+	printed time.Time
+}
+
+// NewProgress returns a progress line for name. total may be set later with SetTotal when it is not known yet.
+func NewProgress(w io.Writer, name string, total int64) *Progress {
+	return &Progress{w: w, name: name, total: total}
+}
+
+func (p *Progress) SetTotal(total int64) {
+	p.total = total
+}
+
+func (p *Progress) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if time.Since(p.printed) >= progressInterval {
+		p.print()
+	}
+	return len(b), nil
+}
+
+// Finish prints the final progress and ends the line.
+func (p *Progress) Finish() {
+	p.print()
+	fmt.Fprintln(p.w)
+}
+
+func (p *Progress) print() {
+	p.printed = time.Now()
+	if p.total <= 0 {
+		fmt.Fprintf(p.w, "\r%s  %s", p.name, formatBytes(p.done))
+		return
+	}
+	fmt.Fprintf(p.w, "\r%s  %s / %s  %3d%%", p.name, formatBytes(p.done), formatBytes(p.total), p.done*100/p.total)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
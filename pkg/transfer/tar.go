@@ -0,0 +1,50 @@
+package transfer
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// TarFile returns a tar stream with the content of r as a single file, as Docker's CopyToContainer expects.
+func TarFile(name string, size int64, mode os.FileMode, r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Size:    size,
+			Mode:    int64(mode.Perm()),
+			ModTime: time.Now(),
+		})
+		if err == nil {
+			_, err = io.Copy(tw, r)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// UntarFile writes the first entry of a tar stream, as returned by Docker's CopyFromContainer, to w.
+func UntarFile(r io.Reader, w io.Writer) error {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("empty archive")
+	}
+	if err != nil {
+		return err
+	}
+	if header.Typeflag != tar.TypeReg {
+		return ErrNotReadable
+	}
+
+	_, err = io.Copy(w, tr)
+	return err
+}
@@ -0,0 +1,219 @@
+// Package transfer copies files to and from remote shells that are only reachable through an
+// interactive session, such as Session Manager and ECS Exec. Files are sent as base64 lines over
+// the session's terminal, delimited by markers that only appear once the remote command runs.
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	SchemeEC2   = "ec2"
+	SchemeECS   = "ecs"
+	SchemeLocal = "local"
+
+	// lineBytes is the number of bytes encoded per line. 57 bytes make 76 base64 characters,
+	// well within the line limit of a terminal in canonical mode.
+	lineBytes = 57
+
+	// endOfFile ends the input of the remote command, as Ctrl-D at the start of a line.
+	endOfFile = "\x04"
+)
+
+var (
+	ErrNotReadable = errors.New("not a readable regular file")
+	ErrInterrupted = errors.New("transfer interrupted")
+)
+
+// targetSegments is the number of path segments naming the target of each scheme.
+var targetSegments = map[string]int{
+	SchemeEC2:   1, // ec2://<name-or-id>/path
+	SchemeECS:   3, // ecs://<cluster>/<service>/<container>/path
+	SchemeLocal: 1, // local://<container>/path
+}
+
+// Location is a file on this machine, or in an instance or container when Scheme is set.
+type Location struct {
+	Scheme string
+	Target []string
+	Path   string
+}
+
+// Parse parses a location URI, or a path on this machine when s has no scheme.
+func Parse(s string) (Location, error) {
+	scheme, rest, found := strings.Cut(s, "://")
+	if !found {
+		return Location{Path: s}, nil
+	}
+
+	segments, ok := targetSegments[scheme]
+	if !ok {
+		return Location{}, fmt.Errorf("unknown scheme %q (expected %s, %s or %s)", scheme, SchemeEC2, SchemeECS, SchemeLocal)
+	}
+
+	parts := strings.SplitN(rest, "/", segments+1)
+	if len(parts) <= segments || parts[segments] == "" {
+		return Location{}, fmt.Errorf("%s: a path is required after the target", s)
+	}
+	for _, part := range parts[:segments] {
+		if part == "" {
+			return Location{}, fmt.Errorf("%s: empty target", s)
+		}
+	}
+
+	return Location{Scheme: scheme, Target: parts[:segments], Path: "/" + parts[segments]}, nil
+}
+
+func (l Location) Remote() bool {
+	return l.Scheme != ""
+}
+
+func (l Location) String() string {
+	if !l.Remote() {
+		return l.Path
+	}
+	return l.Scheme + "://" + strings.Join(l.Target, "/") + l.Path
+}
+
+// Base returns the file name of the location.
+func (l Location) Base() string {
+	return path.Base(l.Path)
+}
+
+// NewToken returns a random token that delimits a transfer in the session output.
+func NewToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// UploadCommand returns the command that writes the base64 lines of its input to path,
+// and prints the OK marker once the file is written.
+func UploadCommand(path, token string) string {
+	script := fmt.Sprintf(`stty -echo 2>/dev/null; base64 -d > %s && printf 'ECLOGIN-%%s-OK\n' %s`,
+		quote(path), quote(token))
+	return "sh -c " + quote(script)
+}
+
+// DownloadCommand returns the command that prints the size and the base64 lines of path between markers.
+func DownloadCommand(path, token string) string {
+	script := fmt.Sprintf(`p=%[1]s; t=%[2]s; if [ -f "$p" ] && [ -r "$p" ]; then `+
+		`printf 'ECLOGIN-%%s-BEGIN %%s\n' "$t" "$(wc -c < "$p" | tr -d ' ')"; base64 < "$p"; printf 'ECLOGIN-%%s-END\n' "$t"; `+
+		`else printf 'ECLOGIN-%%s-ERROR\n' "$t"; fi`,
+		quote(path), quote(token))
+	return "sh -c " + quote(script)
+}
+
+// quote quotes s for a POSIX shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+func marker(token, name string) string {
+	return "ECLOGIN-" + token + "-" + name
+}
+
+// Encode writes the content of r to w as base64 lines for UploadCommand, followed by the end of input.
+func Encode(w io.Writer, r io.Reader) error {
+	buf := make([]byte, lineBytes)
+	line := make([]byte, base64.StdEncoding.EncodedLen(lineBytes)+1)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			encoded := base64.StdEncoding.EncodedLen(n)
+			base64.StdEncoding.Encode(line, buf[:n])
+			line[encoded] = '\n'
+			if _, err := w.Write(line[:encoded+1]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, endOfFile)
+	return err
+}
+
+// Decode reads the session output of DownloadCommand from r and writes the file to w.
+// size is called with the size of the file before its content is written.
+func Decode(r io.Reader, w io.Writer, token string, size func(int64)) error {
+	begin, end, failed := marker(token, "BEGIN")+" ", marker(token, "END"), marker(token, "ERROR")
+
+	scanner := bufio.NewScanner(r)
+	started := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !started {
+			switch {
+			case strings.HasSuffix(line, failed):
+				return ErrNotReadable
+			case strings.Contains(line, begin):
+				n, err := strconv.ParseInt(line[strings.Index(line, begin)+len(begin):], 10, 64)
+				if err == nil && size != nil {
+					size(n)
+				}
+				started = true
+			}
+			continue
+		}
+
+		if line == end {
+			return nil
+		}
+		data, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return fmt.Errorf("failed to decode file content: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ErrInterrupted
+}
+
+// MarkerWriter discards the session output of UploadCommand, remembering whether it reported success.
+type MarkerWriter struct {
+	marker []byte
+	tail   []byte
+	found  bool
+}
+
+func NewMarkerWriter(token string) *MarkerWriter {
+	return &MarkerWriter{marker: []byte(marker(token, "OK"))}
+}
+
+func (m *MarkerWriter) Write(p []byte) (int, error) {
+	if !m.found {
+		data := append(m.tail, p...)
+		m.found = bytes.Contains(data, m.marker)
+		if keep := len(m.marker) - 1; len(data) > keep {
+			data = data[len(data)-keep:]
+		}
+		m.tail = append(m.tail[:0], data...)
+	}
+	return len(p), nil
+}
+
+// Found reports whether the upload succeeded.
+func (m *MarkerWriter) Found() bool {
+	return m.found
+}
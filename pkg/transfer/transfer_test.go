@@ -0,0 +1,164 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Location
+	}{
+		{input: "./app.log", expected: Location{Path: "./app.log"}},
+		{input: "ec2://bastion/var/log/app.log", expected: Location{Scheme: SchemeEC2, Target: []string{"bastion"}, Path: "/var/log/app.log"}},
+		{input: "ecs://main/api/app/tmp/", expected: Location{Scheme: SchemeECS, Target: []string{"main", "api", "app"}, Path: "/tmp/"}},
+		{input: "local://web/etc/nginx/nginx.conf", expected: Location{Scheme: SchemeLocal, Target: []string{"web"}, Path: "/etc/nginx/nginx.conf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			location, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if location.String() != tt.input || location.Scheme != tt.expected.Scheme ||
+				strings.Join(location.Target, "/") != strings.Join(tt.expected.Target, "/") || location.Path != tt.expected.Path {
+				t.Errorf("expected %+v, got %+v", tt.expected, location)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"s3://bucket/key", "ec2://bastion", "ecs://main/api/app", "ecs://main//app/tmp/file"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("expected an error for %s", input)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	content := bytes.Repeat([]byte("eclogin\x00\xff"), 1000)
+
+	var encoded bytes.Buffer
+	if err := Encode(&encoded, bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(encoded.String(), endOfFile) {
+		t.Error("expected the input to end with an end of file")
+	}
+
+	// The session output has noise around the markers and CRLF line endings from the remote terminal.
+	body := strings.ReplaceAll(strings.TrimSuffix(encoded.String(), endOfFile), "\n", "\r\n")
+	output := "Starting session with SessionId: alice-0123\r\n" +
+		"ECLOGIN-token-BEGIN 10000\r\n" + body + "ECLOGIN-token-END\r\n\r\nExiting session\r\n"
+
+	var decoded bytes.Buffer
+	var size int64
+	if err := Decode(strings.NewReader(output), &decoded, "token", func(n int64) { size = n }); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Bytes(), content) || size != 10000 {
+		t.Errorf("expected the content to round trip, got %d bytes of size %d", decoded.Len(), size)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	err := Decode(strings.NewReader("ECLOGIN-token-ERROR\r\n"), io.Discard, "token", nil)
+	if !errors.Is(err, ErrNotReadable) {
+		t.Errorf("expected ErrNotReadable, got %v", err)
+	}
+
+	err = Decode(strings.NewReader("ECLOGIN-token-BEGIN 3\r\nYWJj\r\n"), io.Discard, "token", nil)
+	if !errors.Is(err, ErrInterrupted) {
+		t.Errorf("expected ErrInterrupted, got %v", err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	if _, err := exec.LookPath("base64"); err != nil {
+		t.Skip("base64 is not available")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "it's a file.txt")
+	content := []byte(strings.Repeat("hello, eclogin\n", 100))
+
+	var encoded bytes.Buffer
+	if err := Encode(&encoded, bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	// Without a terminal, the end of file is sent by closing stdin instead.
+	upload := exec.Command("sh", "-c", UploadCommand(path, "token"))
+	upload.Stdin = strings.NewReader(strings.TrimSuffix(encoded.String(), endOfFile))
+	uploadOutput := NewMarkerWriter("token")
+	upload.Stdout = uploadOutput
+	if err := upload.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !uploadOutput.Found() {
+		t.Fatal("expected the upload to succeed")
+	}
+
+	download, err := exec.Command("sh", "-c", DownloadCommand(path, "token")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded bytes.Buffer
+	if err := Decode(bytes.NewReader(download), &decoded, "token", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Bytes(), content) {
+		t.Errorf("expected %q, got %q", content, decoded.Bytes())
+	}
+
+	missing, err := exec.Command("sh", "-c", DownloadCommand(filepath.Join(dir, "missing"), "token")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Decode(bytes.NewReader(missing), io.Discard, "token", nil); !errors.Is(err, ErrNotReadable) {
+		t.Errorf("expected ErrNotReadable, got %v", err)
+	}
+}
+
+func TestMarkerWriter(t *testing.T) {
+	w := NewMarkerWriter("token")
+	for _, chunk := range []string{"noise ECLOGIN-to", "ken-", "OK\r\n"} {
+		w.Write([]byte(chunk))
+	}
+	if !w.Found() {
+		t.Error("expected the marker to be found across writes")
+	}
+}
+
+func TestTar(t *testing.T) {
+	content := []byte("server { listen 80; }\n")
+	archive := TarFile("nginx.conf", int64(len(content)), 0644, bytes.NewReader(content))
+	defer archive.Close()
+
+	var extracted bytes.Buffer
+	if err := UntarFile(archive, &extracted); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(extracted.Bytes(), content) {
+		t.Errorf("expected %q, got %q", content, extracted.Bytes())
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var out bytes.Buffer
+	progress := NewProgress(&out, "app.log", 2048)
+	progress.Write(make([]byte, 1024))
+	progress.Write(make([]byte, 1024))
+	progress.Finish()
+
+	if !strings.HasSuffix(out.String(), "\rapp.log  2.0 KiB / 2.0 KiB  100%\n") {
+		t.Errorf("unexpected progress: %q", out.String())
+	}
+}